@@ -23,6 +23,15 @@ type AniDB struct {
 	Timeout time.Duration // Timeout for the various calls (default: 45s)
 	Logger  *log.Logger   // Logger where HTTP/UDP traffic is logged
 
+	// Whether mylist modifications that fail because the server can't be
+	// reached (banned, offline, etc) are queued in the cache, to be sent
+	// by ReplayMyListQueue (default: true)
+	MyListQueue bool
+
+	// Fraction of a file's duration that must have been played for
+	// ScrobblePath to mark it as watched (default: 0.8)
+	ScrobbleThreshold float64
+
 	udp *udpWrap
 }
 
@@ -31,6 +40,9 @@ func NewAniDB() *AniDB {
 	ret := &AniDB{
 		Timeout: 45 * time.Second,
 		Logger:  log.New(ioutil.Discard, "", log.LstdFlags),
+
+		MyListQueue:       true,
+		ScrobbleThreshold: 0.8,
 	}
 	ret.udp = newUDPWrap(ret)
 	return ret
@@ -73,6 +73,10 @@ type Anime struct {
 
 	Episodes []*Episode // List of episodes.
 
+	Related         []RelatedAnime   // Sequels, prequels, side stories, etc.
+	Similar         []SimilarAnime   // Anime that users consider similar.
+	Recommendations []Recommendation // Recommendations of this anime by users.
+
 	Awards    []string
 	Resources Resources
 
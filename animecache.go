@@ -234,6 +234,32 @@ func (a *Anime) populateFromHTTP(reply httpapi.Anime) bool {
 
 	a.populateResources(reply.Resources)
 
+	a.Related = make([]RelatedAnime, len(reply.RelatedAnime))
+	for i, rel := range reply.RelatedAnime {
+		a.Related[i] = RelatedAnime{
+			AID:   AID(rel.ID),
+			Type:  AnimeRelationType(rel.Type),
+			Title: rel.Title,
+		}
+	}
+	a.Similar = make([]SimilarAnime, len(reply.SimilarAnime))
+	for i, sim := range reply.SimilarAnime {
+		a.Similar[i] = SimilarAnime{
+			AID:      AID(sim.ID),
+			Title:    sim.Title,
+			Approval: sim.Approval,
+			Total:    sim.Total,
+		}
+	}
+	a.Recommendations = make([]Recommendation, len(reply.Recommendations))
+	for i, rec := range reply.Recommendations {
+		a.Recommendations[i] = Recommendation{
+			UID:  UID(rec.ID),
+			Type: rec.Type,
+			Text: rec.Text,
+		}
+	}
+
 	counts := map[misc.EpisodeType]int{}
 
 	sort.Sort(reply.Episodes)
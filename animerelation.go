@@ -0,0 +1,145 @@
+package anidb
+
+import (
+	"sort"
+	"strings"
+)
+
+// See the constants list for the valid values.
+type AnimeRelationType string
+
+const (
+	AnimeRelationSequel             = AnimeRelationType("Sequel")
+	AnimeRelationPrequel            = AnimeRelationType("Prequel")
+	AnimeRelationSameSetting        = AnimeRelationType("Same Setting")
+	AnimeRelationAlternativeSetting = AnimeRelationType("Alternative Setting")
+	AnimeRelationAlternativeVersion = AnimeRelationType("Alternative Version")
+	AnimeRelationSideStory          = AnimeRelationType("Side Story")
+	AnimeRelationParentStory        = AnimeRelationType("Parent Story")
+	AnimeRelationSummary            = AnimeRelationType("Summary")
+	AnimeRelationFullStory          = AnimeRelationType("Full Story")
+	AnimeRelationCharacter          = AnimeRelationType("Character")
+	AnimeRelationMusicVideo         = AnimeRelationType("Music Video")
+	AnimeRelationOther              = AnimeRelationType("Other")
+)
+
+type RelatedAnime struct {
+	AID   AID
+	Type  AnimeRelationType // How the anime is related to the one it's listed in
+	Title string            // Primary title of the related anime
+}
+
+func (r *RelatedAnime) Anime() *Anime {
+	return r.AID.Anime()
+}
+
+type SimilarAnime struct {
+	AID   AID
+	Title string // Primary title of the similar anime
+
+	Approval int // How many users approved of the similarity
+	Total    int // How many users voted on the similarity
+}
+
+func (s *SimilarAnime) Anime() *Anime {
+	return s.AID.Anime()
+}
+
+// Returns the fraction of users that approved of the similarity.
+func (s *SimilarAnime) Ratio() float64 {
+	if s.Total == 0 {
+		return 0
+	}
+	return float64(s.Approval) / float64(s.Total)
+}
+
+type Recommendation struct {
+	UID  UID    // The recommending user
+	Type string // "Recommended", "Must See", etc
+	Text string
+}
+
+func (r *Recommendation) User(adb *AniDB) <-chan *User {
+	return adb.GetUserByID(r.UID)
+}
+
+// Retrieves the given anime in order, sending them as they arrive.
+// Anime that can't be retrieved are skipped.
+func (adb *AniDB) animeList(aids []AID) <-chan *Anime {
+	ch := make(chan *Anime, 10)
+
+	go func() {
+		chs := make([]<-chan *Anime, len(aids))
+		for i, aid := range aids {
+			chs[i] = adb.AnimeByID(aid)
+		}
+		for _, c := range chs {
+			if a := <-c; a != nil {
+				ch <- a
+			}
+		}
+		close(ch)
+	}()
+	return ch
+}
+
+// Retrieves the related anime with any of the given relation types, or all
+// related anime if no type is given. Uses the HTTP and UDP APIs.
+func (a *Anime) RelatedAnime(adb *AniDB, types ...AnimeRelationType) <-chan *Anime {
+	return adb.animeList(a.relatedAIDs(types...))
+}
+
+// The relation types are compared case-insensitively, as the HTTP API
+// doesn't use a consistent case for them.
+func (a *Anime) relatedAIDs(types ...AnimeRelationType) []AID {
+	aids := []AID{}
+	if a != nil {
+		for _, r := range a.Related {
+			match := len(types) == 0
+			for _, t := range types {
+				match = match || strings.EqualFold(string(r.Type), string(t))
+			}
+			if match {
+				aids = append(aids, r.AID)
+			}
+		}
+	}
+	return aids
+}
+
+// Retrieves the sequels of the anime.
+func (a *Anime) Sequels(adb *AniDB) <-chan *Anime {
+	return a.RelatedAnime(adb, AnimeRelationSequel)
+}
+
+// Retrieves the prequels of the anime.
+func (a *Anime) Prequels(adb *AniDB) <-chan *Anime {
+	return a.RelatedAnime(adb, AnimeRelationPrequel)
+}
+
+type similarByRatio []SimilarAnime
+
+func (s similarByRatio) Len() int           { return len(s) }
+func (s similarByRatio) Less(i, j int) bool { return s[i].Ratio() > s[j].Ratio() }
+func (s similarByRatio) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// Retrieves the similar anime, the most approved similarities first.
+// Similarities with fewer than minVotes votes are skipped.
+// Uses the HTTP and UDP APIs.
+func (a *Anime) SimilarAnime(adb *AniDB, minVotes int) <-chan *Anime {
+	list := similarByRatio{}
+	if a != nil {
+		for _, s := range a.Similar {
+			if s.Total >= minVotes {
+				list = append(list, s)
+			}
+		}
+	}
+	sort.Stable(list)
+
+	aids := make([]AID, len(list))
+	for i := range list {
+		aids[i] = list[i].AID
+	}
+	return adb.animeList(aids)
+}
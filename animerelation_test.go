@@ -0,0 +1,26 @@
+package anidb
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRelatedAIDs(T *testing.T) {
+	T.Parallel()
+
+	a := &Anime{Related: []RelatedAnime{
+		{AID: 2, Type: "sequel"},
+		{AID: 3, Type: "Prequel"},
+		{AID: 4, Type: "Same Setting"},
+	}}
+
+	if aids := a.relatedAIDs(AnimeRelationSequel); !reflect.DeepEqual(aids, []AID{2}) {
+		T.Errorf("Expected sequels [2], got %v", aids)
+	}
+	if aids := a.relatedAIDs(AnimeRelationPrequel, AnimeRelationSequel); !reflect.DeepEqual(aids, []AID{2, 3}) {
+		T.Errorf("Expected [2 3], got %v", aids)
+	}
+	if aids := a.relatedAIDs(); len(aids) != 3 {
+		T.Errorf("Expected all related anime, got %v", aids)
+	}
+}
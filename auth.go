@@ -117,6 +117,9 @@ func (udp *udpWrap) ReAuth() udpapi.APIReply {
 		udp.connected = err == nil
 
 		if udp.connected {
+			// send whatever was queued while we were offline
+			defer func() { go udp.adb.ReplayMyListQueue() }()
+
 			if user := UserByName(decrypt(c.username)); user != nil {
 				udp.user = user
 			} else {
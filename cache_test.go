@@ -0,0 +1,29 @@
+package anidb
+
+import (
+	"github.com/Kovensky/go-fscache"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+// Points Cache to an empty temporary directory until the returned function
+// is called. Tests using it must not be parallel.
+func useTestCache(T *testing.T) func() {
+	dir, err := ioutil.TempDir("", "anidb-cache")
+	if err != nil {
+		T.Fatal(err)
+	}
+	c, err := fscache.NewCacheDir(dir)
+	if err != nil {
+		os.RemoveAll(dir)
+		T.Fatal(err)
+	}
+
+	old := Cache
+	Cache = *c
+	return func() {
+		Cache = old
+		os.RemoveAll(dir)
+	}
+}
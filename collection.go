@@ -0,0 +1,153 @@
+package anidb
+
+import (
+	"github.com/Kovensky/go-anidb/misc"
+	"time"
+)
+
+// How complete the current user's collection of an anime is.
+//
+// An episode counts as collected when it's in the mylist with the HDD state.
+type CollectionReport struct {
+	AID AID
+
+	MissingEpisodes misc.EpisodeList // Regular episodes not on HDD.
+	MissingSpecials misc.EpisodeList // Special episodes not on HDD.
+
+	// Missing episodes that are in the mylist, but only as deleted or on CD.
+	NotOnHDD misc.EpisodeList
+
+	// Missing episodes that have already aired, according to their AirDate.
+	AiredMissing misc.EpisodeList
+
+	// The episodes held from each group; same as MyListAnime.EpisodesPerGroup.
+	Groups GroupEpisodes
+	// Whether the regular episodes come from more than one group.
+	MixedGroups bool
+}
+
+func (r *CollectionReport) Anime() *Anime {
+	return r.AID.Anime()
+}
+
+// Returns true if no regular episode is missing.
+func (r *CollectionReport) Complete() bool {
+	return len(r.MissingEpisodes) == 0
+}
+
+func newCollectionReport(a *Anime, mla *MyListAnime) *CollectionReport {
+	r := &CollectionReport{
+		AID:    a.AID,
+		Groups: mla.EpisodesPerGroup,
+	}
+
+	regular := a.TotalEpisodes
+	if regular < a.EpisodeCount.RegularCount {
+		regular = a.EpisodeCount.RegularCount
+	}
+	specials := a.EpisodeCount.SpecialCount
+
+	// episodes without an Episode entry are checked too
+	expected := []misc.Episode{}
+	for i := 1; i <= regular; i++ {
+		expected = append(expected, misc.Episode{Type: misc.EpisodeTypeRegular, Number: i})
+	}
+	for i := 1; i <= specials; i++ {
+		expected = append(expected, misc.Episode{Type: misc.EpisodeTypeSpecial, Number: i})
+	}
+	for _, ep := range a.Episodes {
+		switch {
+		case ep.Type == misc.EpisodeTypeRegular && ep.Number > regular,
+			ep.Type == misc.EpisodeTypeSpecial && ep.Number > specials:
+			expected = append(expected, ep.Episode)
+		}
+	}
+
+	hdd := mla.EpisodesWithState[MyListStateHDD]
+	other := append(misc.EpisodeList{},
+		mla.EpisodesWithState[MyListStateCD]...)
+	other = append(other, mla.EpisodesWithState[MyListStateDeleted]...)
+
+	now := time.Now()
+	for i := range expected {
+		ep := &expected[i]
+		if hdd.ContainsEpisodes(ep) {
+			continue
+		}
+
+		if ep.Type == misc.EpisodeTypeRegular {
+			r.MissingEpisodes.Add(ep)
+		} else {
+			r.MissingSpecials.Add(ep)
+		}
+		if other.ContainsEpisodes(ep) {
+			r.NotOnHDD.Add(ep)
+		}
+		for _, e := range a.EpisodeList(ep) {
+			if e.AirDate != nil && e.AirDate.Before(now) {
+				r.AiredMissing.Add(ep)
+				break
+			}
+		}
+	}
+
+	groups := 0
+	for _, el := range mla.EpisodesPerGroup {
+		for _, er := range el {
+			if er != nil && er.Type == misc.EpisodeTypeRegular {
+				groups++
+				break
+			}
+		}
+	}
+	r.MixedGroups = groups > 1
+
+	return r
+}
+
+// Returns the current user's collection report for the given anime.
+//
+// Returns nil if the anime isn't in the mylist, or on API errors.
+func (adb *AniDB) CollectionReport(aid AID) <-chan *CollectionReport {
+	ch := make(chan *CollectionReport, 1)
+
+	go func() {
+		ach := adb.AnimeByID(aid)
+		mla := <-adb.MyListAnime(aid)
+		a := <-ach
+
+		if a == nil || mla == nil {
+			ch <- nil
+		} else {
+			ch <- newCollectionReport(a, mla)
+		}
+		close(ch)
+	}()
+	return ch
+}
+
+// Returns the collection reports of every anime in the current user's
+// MyListMirror that's missing regular episodes.
+//
+// The list of anime comes from the mirror, which should be synced
+// beforehand.
+func (adb *AniDB) IncompleteSeries() <-chan *CollectionReport {
+	ch := make(chan *CollectionReport, 10)
+
+	go func() {
+		defer close(ch)
+
+		user := <-adb.GetCurrentUser()
+		if user == nil || user.UID < 1 {
+			return
+		}
+		m := &MyListMirror{adb: adb, UID: user.UID}
+
+		for _, aid := range m.AIDs() {
+			if r := <-adb.CollectionReport(aid); r != nil && !r.Complete() {
+				ch <- r
+			}
+		}
+	}()
+	return ch
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/Kovensky/go-anidb"
+	"os"
+	"os/signal"
+	"time"
+)
+
+var (
+	username = flag.String("username", "", "AniDB Username")
+	password = flag.String("password", "", "AniDB Password")
+	apikey   = flag.String("apikey", "", "UDP API key (optional)")
+	storage  = flag.String("storage", "", "Storage label for new files (optional)")
+	interval = flag.Duration("interval", 5*time.Minute, "How often to rescan the directories")
+)
+
+func main() {
+	flag.Parse()
+
+	if *username == "" || *password == "" {
+		fmt.Println("Username and password must be supplied")
+		os.Exit(1)
+	}
+	if len(flag.Args()) == 0 {
+		fmt.Println("No directories to watch")
+		os.Exit(1)
+	}
+
+	adb := anidb.NewAniDB()
+	adb.SetCredentials(*username, *password, *apikey)
+	defer adb.Logout()
+
+	w := adb.NewWatcher(flag.Args()...)
+	w.Interval = *interval
+	if *storage != "" {
+		w.Set.Storage = storage
+	}
+
+	events := make(chan *anidb.WatchEvent, 10)
+	w.Events = events
+
+	stop := make(chan bool)
+	go func() {
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, os.Interrupt)
+		<-sig
+		close(stop)
+	}()
+
+	done := make(chan bool)
+	go func() {
+		w.Run(stop)
+		close(done)
+	}()
+
+	for {
+		select {
+		case ev := <-events:
+			if ev.OldPath != "" {
+				fmt.Printf("%s: %s -> %s\n", ev.Type, ev.OldPath, ev.Path)
+			} else {
+				fmt.Printf("%s: %s\n", ev.Type, ev.Path)
+			}
+		case <-done:
+			return
+		}
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/Kovensky/go-anidb/misc"
 	"image"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -94,6 +95,39 @@ func (f *File) UserMyList(user *User) *MyListEntry {
 	return nil
 }
 
+// Chooses one File out of several candidates that share the same
+// Ed2kHash and Filesize. Returns nil if none of the candidates is acceptable.
+type FileDisambiguator func(candidates []*File) *File
+
+// Returns the first candidate that isn't deprecated; if all of them are,
+// returns the first candidate.
+func PreferNonDeprecated(candidates []*File) *File {
+	for _, f := range candidates {
+		if f != nil && !f.Deprecated {
+			return f
+		}
+	}
+	if len(candidates) > 0 {
+		return candidates[0]
+	}
+	return nil
+}
+
+// Returns a FileDisambiguator that chooses the candidate whose CRC32
+// matches the given (usually locally computed) CRC32. If more than one
+// candidate matches, the non-deprecated one is preferred.
+func MatchCRC32(crc32 string) FileDisambiguator {
+	return func(candidates []*File) *File {
+		matches := make([]*File, 0, len(candidates))
+		for _, f := range candidates {
+			if f != nil && strings.EqualFold(f.CRC32, crc32) {
+				matches = append(matches, f)
+			}
+		}
+		return PreferNonDeprecated(matches)
+	}
+}
+
 type RelatedEpisodes map[EID]float32
 
 func (er RelatedEpisodes) MarshalJSON() ([]byte, error) {
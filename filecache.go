@@ -31,10 +31,27 @@ func (f *File) IsStale() bool {
 
 func cacheFile(f *File) {
 	CacheSet(f.AID, "aid", "by-eid", f.EID)
-	CacheSet(f.FID, "fid", "by-ed2k", f.Ed2kHash, f.Filesize)
+
+	// don't clobber the candidate list of an ed2k+size collision
+	var fids []FID
+	Cache.Get(&fids, "fid", "by-ed2k", f.Ed2kHash, f.Filesize)
+	if !containsFID(fids, f.FID) {
+		fids = append(fids, f.FID)
+		CacheSet(&fids, "fid", "by-ed2k", f.Ed2kHash, f.Filesize)
+	}
+
 	CacheSet(f, "fid", f.FID)
 }
 
+func containsFID(fids []FID, fid FID) bool {
+	for _, id := range fids {
+		if id == fid {
+			return true
+		}
+	}
+	return false
+}
+
 type FID int
 
 func (fid FID) File() *File {
@@ -119,24 +136,92 @@ func (adb *AniDB) FileByID(fid FID) <-chan *File {
 var validEd2kHash = regexp.MustCompile(`\A[[:xdigit:]]{32}\z`)
 
 // Retrieves a File by its Ed2kHash + Filesize combination. Uses the UDP API.
+//
+// If more than one File matches, the non-deprecated one is preferred.
+// Equivalent to FileByEd2kSizeWith(ed2k, size, PreferNonDeprecated).
 func (adb *AniDB) FileByEd2kSize(ed2k string, size int64) <-chan *File {
-	key := []fscache.CacheKey{"fid", "by-ed2k", ed2k, size}
+	return adb.FileByEd2kSizeWith(ed2k, size, PreferNonDeprecated)
+}
 
+// Retrieves a File by its Ed2kHash + Filesize combination. Uses the UDP API.
+//
+// If more than one File matches, the given FileDisambiguator is used to
+// choose between them. If pick is nil, no File is returned in that case.
+func (adb *AniDB) FileByEd2kSizeWith(ed2k string, size int64, pick FileDisambiguator) <-chan *File {
 	ch := make(chan *File, 1)
 
+	fileChan := adb.FilesByEd2kSize(ed2k, size)
+
+	go func() {
+		var files []*File
+		for f := range fileChan {
+			if f != nil {
+				files = append(files, f)
+			}
+		}
+
+		switch {
+		case len(files) == 1:
+			ch <- files[0]
+		case len(files) > 1 && pick != nil:
+			if f := pick(files); f != nil {
+				ch <- f
+			}
+		}
+		close(ch)
+	}()
+	return ch
+}
+
+// Retrieves all Files that match the given Ed2kHash + Filesize combination.
+// Usually there's only one, but collisions do exist.
+// The returned channel may return multiple (or no) Files. Uses the UDP API.
+//
+// On API error (offline, etc), the first *File returned is nil,
+// followed by cached files (which may also be nil).
+func (adb *AniDB) FilesByEd2kSize(ed2k string, size int64) <-chan *File {
+	ch := make(chan *File, 10)
+
+	fidChan := adb.FIDsByEd2kSize(ed2k, size)
+
+	go func() {
+		chs := []<-chan *File{}
+		for fid := range fidChan {
+			chs = append(chs, adb.FileByID(fid))
+		}
+		for _, c := range chs {
+			for f := range c {
+				ch <- f
+			}
+		}
+		close(ch)
+	}()
+	return ch
+}
+
+// Retrieves the FIDs of all Files that match the given Ed2kHash + Filesize
+// combination. The returned channel may return multiple (or no) FIDs.
+// Uses the UDP API.
+//
+// On API error (offline, etc), the first FID returned is 0,
+// followed by cached FIDs.
+func (adb *AniDB) FIDsByEd2kSize(ed2k string, size int64) <-chan FID {
+	ch := make(chan FID, 10)
+
 	if size < 1 || !validEd2kHash.MatchString(ed2k) {
-		ch <- nil
+		ch <- 0
 		close(ch)
 		return ch
 	}
 	// AniDB always uses lower case hashes
 	ed2k = strings.ToLower(ed2k)
 
+	key := []fscache.CacheKey{"fid", "by-ed2k", ed2k, size}
+
 	ic := make(chan notification, 1)
 	go func() {
-		fid := (<-ic).(FID)
-		if fid > 0 {
-			ch <- <-adb.FileByID(fid)
+		for c := range ic {
+			ch <- c.(FID)
 		}
 		close(ch)
 	}()
@@ -145,15 +230,22 @@ func (adb *AniDB) FileByEd2kSize(ed2k string, size int64) <-chan *File {
 	}
 
 	if !Cache.IsValid(InvalidKeyCacheDuration, key...) {
-		intentMap.NotifyClose(FID(0), key...)
+		intentMap.Close(key...)
 		return ch
 	}
 
-	fid := FID(0)
-
-	switch ts, err := Cache.Get(&fid, key...); {
+	var fids []FID
+	switch ts, err := Cache.Get(&fids, key...); {
 	case err == nil && time.Now().Sub(ts) < FileCacheDuration:
-		intentMap.NotifyClose(fid, key...)
+		is := intentMap.LockIntent(key...)
+		go func() {
+			defer intentMap.Free(is, key...)
+			defer is.Close()
+
+			for _, fid := range fids {
+				is.Notify(fid)
+			}
+		}()
 		return ch
 	}
 
@@ -166,20 +258,44 @@ func (adb *AniDB) FileByEd2kSize(ed2k string, size int64) <-chan *File {
 				"amask": fileAmask,
 			})
 
-		var f *File
-		if reply.Error() == nil {
-			adb.parseFileResponse(&f, reply, false)
+		is := intentMap.LockIntent(key...)
+		defer intentMap.Free(is, key...)
 
-			fid = f.FID
+		switch reply.Code() {
+		case 220:
+			var f *File
+			if adb.parseFileResponse(&f, reply, false) {
+				fids = []FID{f.FID}
 
-			cacheFile(f)
-		} else if reply.Code() == 320 { // file not found
+				cacheFile(f)
+				CacheSet(&fids, key...)
+
+				is.NotifyClose(f.FID)
+			} else {
+				is.NotifyClose(FID(0))
+			}
+			return
+		case 322: // multiple files found
+			parts := strings.Split(reply.Lines()[1], "|")
+			fids = make([]FID, len(parts))
+			for i := range parts {
+				id, _ := strconv.ParseInt(parts[i], 10, 32)
+				fids[i] = FID(id)
+			}
+
+			CacheSet(&fids, key...)
+		case 320: // file not found
 			Cache.SetInvalid(key...)
-		} else if reply.Code() == 322 { // multiple files found
-			panic("Don't know what to do with " + strings.Join(reply.Lines(), "\n"))
+			is.Close()
+			return
+		default:
+			is.Notify(FID(0))
 		}
 
-		intentMap.NotifyClose(fid, key...)
+		defer is.Close()
+		for _, fid := range fids {
+			is.Notify(fid)
+		}
 	}()
 	return ch
 }
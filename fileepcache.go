@@ -1,6 +1,7 @@
 package anidb
 
 import (
+	"github.com/Kovensky/go-anidb/misc"
 	"github.com/Kovensky/go-fscache"
 	"strconv"
 	"strings"
@@ -144,3 +145,142 @@ func (adb *AniDB) FIDsByGID(ep *Episode, gid GID) <-chan FID {
 	}()
 	return ch
 }
+
+// Gets all Files released for the given Episode, by any Group.
+// The returned channel may return multiple (or no) Files.
+// Uses the UDP API.
+//
+// On API error (offline, etc), the first *File returned is nil,
+// followed by the files that could be found.
+func (adb *AniDB) FilesForEpisode(ep *Episode) <-chan *File {
+	ch := make(chan *File, 10)
+
+	fidChan := adb.FIDsForEpisode(ep)
+
+	go func() {
+		chs := []<-chan *File{}
+		for fid := range fidChan {
+			chs = append(chs, adb.FileByID(fid))
+		}
+		for _, c := range chs {
+			for f := range c {
+				ch <- f
+			}
+		}
+		close(ch)
+	}()
+	return ch
+}
+
+// The completion states queried by FIDsForEpisode; 0 is the server's
+// default filter, the others are the states it leaves out.
+var episodeGroupStates = []GroupCompletionState{
+	0,
+	GroupCompletionStalled,
+	GroupCompletionDropped,
+	GroupCompletionSpecialsOnly,
+}
+
+// Gets the FIDs of all Files released for the given Episode, by any Group.
+// The Groups are found with GroupStatus, and their files with FIDsByGID.
+// The returned channel may return multiple (or no) FIDs. Uses the UDP API.
+//
+// On API error (offline, etc), the first FID returned is 0,
+// followed by the FIDs that could be found.
+func (adb *AniDB) FIDsForEpisode(ep *Episode) <-chan FID {
+	ch := make(chan FID, 10)
+
+	if ep == nil {
+		ch <- 0
+		close(ch)
+		return ch
+	}
+
+	key := []fscache.CacheKey{"fid", "by-eid", ep.EID}
+
+	ic := make(chan notification, 1)
+	go func() {
+		for c := range ic {
+			ch <- c.(FID)
+		}
+		close(ch)
+	}()
+	if intentMap.Intent(ic, key...) {
+		return ch
+	}
+
+	var fids []FID
+	switch ts, err := Cache.Get(&fids, key...); {
+	case err == nil && time.Now().Sub(ts) < GroupStatusCacheDuration:
+		is := intentMap.LockIntent(key...)
+		go func() {
+			defer intentMap.Free(is, key...)
+			defer is.Close()
+
+			for _, fid := range fids {
+				is.Notify(fid)
+			}
+		}()
+		return ch
+	}
+
+	go func() {
+		apiError := false
+
+		gsChans := make([]<-chan *GroupStatus, len(episodeGroupStates))
+		for i, state := range episodeGroupStates {
+			gsChans[i] = adb.GroupStatus(ep.AID, state)
+		}
+
+		seenGID := map[GID]bool{}
+		fidChans := []<-chan FID{}
+		for _, c := range gsChans {
+			for gs := range c {
+				if gs == nil {
+					apiError = true
+					continue
+				}
+				if seenGID[gs.GID] {
+					continue
+				}
+				// The released ranges only reliably describe regular episodes;
+				// for the other types we have to ask every group.
+				if ep.Type == misc.EpisodeTypeRegular && !gs.Episodes.ContainsEpisodes(&ep.Episode) {
+					continue
+				}
+				seenGID[gs.GID] = true
+				fidChans = append(fidChans, adb.FIDsByGID(ep, gs.GID))
+			}
+		}
+
+		seenFID := map[FID]bool{}
+		fids = []FID{}
+		for _, c := range fidChans {
+			for fid := range c {
+				if fid == 0 {
+					apiError = true
+					continue
+				}
+				if !seenFID[fid] {
+					seenFID[fid] = true
+					fids = append(fids, fid)
+				}
+			}
+		}
+
+		is := intentMap.LockIntent(key...)
+		defer intentMap.Free(is, key...)
+
+		if apiError {
+			is.Notify(FID(0))
+		} else {
+			CacheSet(&fids, key...)
+		}
+
+		defer is.Close()
+		for _, fid := range fids {
+			is.Notify(fid)
+		}
+	}()
+	return ch
+}
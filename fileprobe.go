@@ -0,0 +1,224 @@
+package anidb
+
+import (
+	"fmt"
+	"github.com/Kovensky/go-anidb/probe"
+	"image"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Container codec IDs, in the names AniDB uses (after sanitizeCodec).
+var probeCodecs = map[string]string{
+	"V_MPEG4/ISO/AVC":  "H.264",
+	"avc1":             "H.264",
+	"V_MPEGH/ISO/HEVC": "HEVC",
+	"hvc1":             "HEVC",
+	"hev1":             "HEVC",
+	"V_VP9":            "VP9",
+	"vp09":             "VP9",
+	"V_AV1":            "AV1",
+	"av01":             "AV1",
+	"V_MPEG4/ISO/ASP":  "ASP Other",
+	"mp4v":             "ASP Other",
+
+	"A_AAC":     "AAC",
+	"mp4a":      "AAC",
+	"A_AC3":     "AC3",
+	"ac-3":      "AC3",
+	"A_EAC3":    "E-AC3",
+	"ec-3":      "E-AC3",
+	"A_DTS":     "DTS",
+	"A_FLAC":    "FLAC",
+	"fLaC":      "FLAC",
+	"A_VORBIS":  "Vorbis",
+	"A_OPUS":    "Opus",
+	"Opus":      "Opus",
+	"A_MPEG/L3": "MP3",
+	"A_TRUEHD":  "TrueHD",
+}
+
+// ISO 639-2 codes, in the names AniDB uses.
+var probeLanguages = map[string]Language{
+	"jpn": "japanese",
+	"eng": "english",
+	"ger": "german",
+	"deu": "german",
+	"fre": "french",
+	"fra": "french",
+	"spa": "spanish",
+	"ita": "italian",
+	"por": "portuguese",
+	"rus": "russian",
+	"pol": "polish",
+	"chi": "chinese (unspecified)",
+	"zho": "chinese (unspecified)",
+	"kor": "korean",
+	"ara": "arabic",
+}
+
+func probeCodec(codec string) string {
+	if c, ok := probeCodecs[codec]; ok {
+		return c
+	}
+	return codec
+}
+
+func probeLanguage(lang string) Language {
+	if l, ok := probeLanguages[lang]; ok {
+		return l
+	}
+	return Language(lang)
+}
+
+// Reads the stream information of a local MKV or MP4 file (see the probe
+// package), and returns it as a File. Only the Filesize, Length,
+// FileExtension, VideoInfo, AudioStreams and SubtitleLanguages fields are
+// set. Bitrates aren't known.
+func ProbeFile(path string) (*File, error) {
+	info, err := probe.File(path)
+	if err != nil {
+		return nil, err
+	}
+	stat, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &File{
+		Filesize:      stat.Size(),
+		Length:        info.Duration,
+		FileExtension: strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), "."),
+
+		AudioStreams:      make([]AudioStream, len(info.Audio)),
+		SubtitleLanguages: make([]Language, len(info.Subtitles)),
+	}
+
+	if len(info.Video) > 0 {
+		v := info.Video[0]
+		f.VideoInfo = VideoInfo{
+			Codec:      probeCodec(v.Codec),
+			Resolution: image.Rect(0, 0, v.Width, v.Height),
+			ColorDepth: v.ColorDepth,
+		}
+	}
+	for i, a := range info.Audio {
+		f.AudioStreams[i] = AudioStream{
+			Codec:    probeCodec(a.Codec),
+			Language: probeLanguage(a.Language),
+		}
+	}
+	for i, s := range info.Subtitles {
+		f.SubtitleLanguages[i] = probeLanguage(s.Language)
+	}
+
+	return f, nil
+}
+
+// Empty lists from AniDB come as a single empty entry, so these
+// don't count empty entries.
+func audioStreamCount(f *File) (n int) {
+	for _, a := range f.AudioStreams {
+		if a.Codec != "" {
+			n++
+		}
+	}
+	return
+}
+
+func subtitleCount(f *File) (n int) {
+	for _, l := range f.SubtitleLanguages {
+		if l != "" {
+			n++
+		}
+	}
+	return
+}
+
+// Fills the stream information missing from the File with the probed data
+// (see ProbeFile), and updates the cache. The cache timestamp is kept, so
+// Incomplete files are still refreshed from AniDB when they become stale.
+//
+// Returns whether anything was filled.
+func (f *File) FillFromProbe(p *File) bool {
+	if f == nil || p == nil {
+		return false
+	}
+	changed := false
+
+	if f.Length == 0 && p.Length > 0 {
+		f.Length = p.Length
+		changed = true
+	}
+	if f.FileExtension == "" && p.FileExtension != "" {
+		f.FileExtension = p.FileExtension
+		changed = true
+	}
+	if f.VideoInfo.Codec == "" && p.VideoInfo.Codec != "" {
+		f.VideoInfo.Codec = p.VideoInfo.Codec
+		changed = true
+	}
+	if f.VideoInfo.Resolution.Empty() && !p.VideoInfo.Resolution.Empty() {
+		f.VideoInfo.Resolution = p.VideoInfo.Resolution
+		changed = true
+	}
+	if f.VideoInfo.ColorDepth == 0 && p.VideoInfo.ColorDepth > 0 {
+		f.VideoInfo.ColorDepth = p.VideoInfo.ColorDepth
+		changed = true
+	}
+	if audioStreamCount(f) == 0 && len(p.AudioStreams) > 0 {
+		f.AudioStreams = p.AudioStreams
+		changed = true
+	}
+	if subtitleCount(f) == 0 && len(p.SubtitleLanguages) > 0 {
+		f.SubtitleLanguages = p.SubtitleLanguages
+		changed = true
+	}
+
+	if changed && f.FID > 0 {
+		Cache.Set(f, "fid", f.FID)
+		Cache.Chtime(f.Cached, "fid", f.FID)
+	}
+	return changed
+}
+
+// Compares the File against the probed data (see ProbeFile), and describes
+// every difference. Fields missing from either side aren't compared.
+func (f *File) ProbeMismatches(p *File) (diffs []string) {
+	if f == nil || p == nil {
+		return nil
+	}
+
+	if f.Filesize > 0 && f.Filesize != p.Filesize {
+		diffs = append(diffs, fmt.Sprintf("size: %d on AniDB, %d on disk", f.Filesize, p.Filesize))
+	}
+	// AniDB rounds lengths, so allow some slack
+	if d := f.Length - p.Length; f.Length > 0 && p.Length > 0 && (d > time.Minute || d < -time.Minute) {
+		diffs = append(diffs, fmt.Sprintf("length: %v on AniDB, %v on disk", f.Length, p.Length))
+	}
+
+	fr, pr := f.VideoInfo.Resolution, p.VideoInfo.Resolution
+	if !fr.Empty() && !pr.Empty() && (fr.Dx() != pr.Dx() || fr.Dy() != pr.Dy()) {
+		diffs = append(diffs, fmt.Sprintf("resolution: %dx%d on AniDB, %dx%d on disk",
+			fr.Dx(), fr.Dy(), pr.Dx(), pr.Dy()))
+	}
+	if fc, pc := f.VideoInfo.Codec, p.VideoInfo.Codec; fc != "" && pc != "" && !strings.EqualFold(fc, pc) {
+		diffs = append(diffs, fmt.Sprintf("video codec: %s on AniDB, %s on disk", fc, pc))
+	}
+	// 0 is an unknown depth, which the probe gives for most codecs
+	if fd, pd := f.VideoInfo.ColorDepth, p.VideoInfo.ColorDepth; fd > 0 && pd > 0 && fd != pd {
+		diffs = append(diffs, fmt.Sprintf("color depth: %d on AniDB, %d on disk", fd, pd))
+	}
+
+	if audio := audioStreamCount(f); audio > 0 && audio != len(p.AudioStreams) {
+		diffs = append(diffs, fmt.Sprintf("audio streams: %d on AniDB, %d on disk",
+			audio, len(p.AudioStreams)))
+	}
+	if subs := subtitleCount(f); subs > 0 && subs != len(p.SubtitleLanguages) {
+		diffs = append(diffs, fmt.Sprintf("subtitle streams: %d on AniDB, %d on disk",
+			subs, len(p.SubtitleLanguages)))
+	}
+	return
+}
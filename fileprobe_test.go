@@ -0,0 +1,27 @@
+package anidb
+
+import (
+	"testing"
+)
+
+func TestProbeColorDepth(T *testing.T) {
+	T.Parallel()
+
+	probed := &File{VideoInfo: VideoInfo{ColorDepth: 10}}
+
+	f := &File{}
+	if diffs := f.ProbeMismatches(probed); len(diffs) != 0 {
+		T.Error("Unknown color depth shouldn't be compared:", diffs)
+	}
+	if !f.FillFromProbe(probed) || f.VideoInfo.ColorDepth != 10 {
+		T.Error("Unknown color depth wasn't filled:", f.VideoInfo.ColorDepth)
+	}
+
+	f = &File{VideoInfo: VideoInfo{ColorDepth: 8}}
+	if diffs := f.ProbeMismatches(probed); len(diffs) != 1 {
+		T.Error("Expected a color depth mismatch, got", diffs)
+	}
+	if f.FillFromProbe(probed) || f.VideoInfo.ColorDepth != 8 {
+		T.Error("Known color depth was overwritten:", f.VideoInfo.ColorDepth)
+	}
+}
@@ -20,6 +20,10 @@ var (
 
 	LIDCacheDuration = 4 * DefaultCacheDuration
 
+	GroupStatusCacheDuration = 24 * time.Hour // Changes whenever a group releases something.
+
+	VoteCacheDuration = DefaultCacheDuration
+
 	UIDCacheDuration = 16 * DefaultCacheDuration // Can these even be changed?
 
 	// Used for anime that have already finished airing.
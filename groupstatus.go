@@ -0,0 +1,53 @@
+package anidb
+
+import (
+	"github.com/Kovensky/go-anidb/misc"
+)
+
+// See the constants list for valid values.
+type GroupCompletionState int
+
+const (
+	GroupCompletionOngoing = GroupCompletionState(1 + iota)
+	GroupCompletionStalled
+	GroupCompletionComplete
+	GroupCompletionDropped
+	GroupCompletionFinished
+	GroupCompletionSpecialsOnly
+)
+
+func (gs GroupCompletionState) String() string {
+	switch gs {
+	case GroupCompletionOngoing:
+		return "Ongoing"
+	case GroupCompletionStalled:
+		return "Stalled"
+	case GroupCompletionComplete:
+		return "Complete"
+	case GroupCompletionDropped:
+		return "Dropped"
+	case GroupCompletionFinished:
+		return "Finished"
+	case GroupCompletionSpecialsOnly:
+		return "Specials Only"
+	default:
+		return "Unknown"
+	}
+}
+
+// The release status of a Group for a given Anime.
+type GroupStatus struct {
+	GID  GID
+	Name string // The group's full name
+
+	State GroupCompletionState
+
+	LastEpisode int    // Number of the last regular episode released
+	Rating      Rating // Rating of the group's releases for this anime
+
+	Episodes misc.EpisodeList // Episodes the group has released for this anime
+}
+
+func (gs *GroupStatus) Group() *Group {
+	return gs.GID.Group()
+}
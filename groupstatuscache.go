@@ -0,0 +1,136 @@
+package anidb
+
+import (
+	"github.com/Kovensky/go-anidb/misc"
+	"github.com/Kovensky/go-fscache"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Gets the release status of all Groups that have worked on
+// the given Anime. The returned channel may return multiple
+// (or no) GroupStatus. Uses the UDP API.
+//
+// If state is 0, the server's default filter is used, which only
+// returns ongoing, finished and complete groups. Otherwise,
+// only the groups with the given completion state are returned.
+//
+// On API error (offline, etc), the first *GroupStatus returned is nil,
+// followed by cached statuses.
+func (adb *AniDB) GroupStatus(aid AID, state GroupCompletionState) <-chan *GroupStatus {
+	key := []fscache.CacheKey{"groupstatus", aid, int(state)}
+
+	ch := make(chan *GroupStatus, 10)
+
+	if aid < 1 || state < 0 {
+		ch <- nil
+		close(ch)
+		return ch
+	}
+
+	ic := make(chan notification, 1)
+	go func() {
+		for c := range ic {
+			ch <- c.(*GroupStatus)
+		}
+		close(ch)
+	}()
+	if intentMap.Intent(ic, key...) {
+		return ch
+	}
+
+	if !Cache.IsValid(InvalidKeyCacheDuration, key...) {
+		intentMap.Close(key...)
+		return ch
+	}
+
+	var list []GroupStatus
+	switch ts, err := Cache.Get(&list, key...); {
+	case err == nil && time.Now().Sub(ts) < GroupStatusCacheDuration:
+		is := intentMap.LockIntent(key...)
+		go func() {
+			defer intentMap.Free(is, key...)
+			defer is.Close()
+
+			for i := range list {
+				is.Notify(&list[i])
+			}
+		}()
+		return ch
+	}
+
+	go func() {
+		pm := paramMap{"aid": aid}
+		if state > 0 {
+			pm["state"] = int(state)
+		}
+		reply := <-adb.udp.SendRecv("GROUPSTATUS", pm)
+
+		is := intentMap.LockIntent(key...)
+		defer intentMap.Free(is, key...)
+
+		switch reply.Code() {
+		case 225:
+			lines := reply.Lines()[1:]
+			if reply.Truncated() {
+				// the last line is likely incomplete
+				adb.Logger.Printf("UDP!!! GROUPSTATUS for AID %d truncated, dropping the last group", aid)
+				lines = lines[:len(lines)-1]
+			}
+
+			list = make([]GroupStatus, 0, len(lines))
+			for _, line := range lines {
+				if gs := parseGroupStatusLine(line); gs != nil {
+					list = append(list, *gs)
+				}
+			}
+
+			if !reply.Truncated() {
+				CacheSet(&list, key...)
+			}
+		case 325: // no groups found
+			list = []GroupStatus{}
+			CacheSet(&list, key...)
+		case 330: // no such anime
+			Cache.SetInvalid(key...)
+			is.Close()
+			return
+		default:
+			is.Notify((*GroupStatus)(nil))
+		}
+
+		defer is.Close()
+		for i := range list {
+			is.Notify(&list[i])
+		}
+	}()
+	return ch
+}
+
+func parseGroupStatusLine(line string) *GroupStatus {
+	parts := strings.Split(line, "|")
+	if len(parts) < 7 {
+		return nil
+	}
+
+	ints := make([]int64, len(parts))
+	for i := range parts {
+		ints[i], _ = strconv.ParseInt(parts[i], 10, 32)
+	}
+
+	return &GroupStatus{
+		GID:  GID(ints[0]),
+		Name: parts[1],
+
+		State: GroupCompletionState(ints[2]),
+
+		LastEpisode: int(ints[3]),
+		Rating: Rating{
+			Rating:    float32(ints[4]) / 100,
+			VoteCount: int(ints[5]),
+		},
+
+		Episodes: misc.ParseEpisodeList(parts[6]),
+	}
+}
@@ -0,0 +1,89 @@
+// Single-pass hashing of local files, computing every hash AniDB knows
+// about (ed2k, CRC32, SHA1 and MD5) with one read of the file.
+package hash
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"encoding/hex"
+	ed2khash "github.com/Kovensky/go-ed2k"
+	"hash"
+	"hash/crc32"
+	"io"
+)
+
+// The hashes of a file, as lowercase hex strings.
+type Hashes struct {
+	Size int64
+
+	Ed2k  string
+	CRC32 string
+	SHA1  string
+	MD5   string
+}
+
+// Reports how many bytes of a file have been hashed so far,
+// out of its total size.
+type ProgressFunc func(path string, done, total int64)
+
+// How often progress is reported, in bytes.
+const progressInterval = 1 << 20
+
+type progressWriter struct {
+	path     string
+	total    int64
+	done     int64
+	reported int64
+	progress ProgressFunc
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	w.done += int64(len(p))
+	if w.done-w.reported >= progressInterval || w.done == w.total {
+		w.reported = w.done
+		w.progress(w.path, w.done, w.total)
+	}
+	return len(p), nil
+}
+
+// Hashes everything read from r. The path and total are only used for
+// the progress callback, which may be nil.
+func hashReader(r io.Reader, path string, total int64, progress ProgressFunc) (*Hashes, error) {
+	hashes := []hash.Hash{
+		ed2khash.New(true),
+		crc32.NewIEEE(),
+		sha1.New(),
+		md5.New(),
+	}
+
+	writers := make([]io.Writer, len(hashes), len(hashes)+1)
+	for i := range hashes {
+		writers[i] = hashes[i]
+	}
+	if progress != nil {
+		writers = append(writers, &progressWriter{path: path, total: total, progress: progress})
+	}
+
+	n, err := io.Copy(io.MultiWriter(writers...), r)
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 && progress != nil {
+		// the progressWriter never saw a write
+		progress(path, 0, total)
+	}
+
+	return &Hashes{
+		Size: n,
+
+		Ed2k:  hex.EncodeToString(hashes[0].Sum(nil)),
+		CRC32: hex.EncodeToString(hashes[1].Sum(nil)),
+		SHA1:  hex.EncodeToString(hashes[2].Sum(nil)),
+		MD5:   hex.EncodeToString(hashes[3].Sum(nil)),
+	}, nil
+}
+
+// Hashes everything read from r, without caching or progress reports.
+func Reader(r io.Reader) (*Hashes, error) {
+	return hashReader(r, "", 0, nil)
+}
@@ -0,0 +1,146 @@
+package hash
+
+import (
+	"github.com/Kovensky/go-fscache"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestReader(T *testing.T) {
+	T.Parallel()
+
+	h, err := Reader(strings.NewReader("The quick brown fox jumps over the lazy dog"))
+	if err != nil {
+		T.Fatal(err)
+	}
+
+	if h.Size != 43 {
+		T.Error("Wrong size:", h.Size)
+	}
+	if h.CRC32 != "414fa339" {
+		T.Error("Wrong CRC32:", h.CRC32)
+	}
+	if h.SHA1 != "2fd4e1c67a2d28fced849ee1bb76e7391b93eb12" {
+		T.Error("Wrong SHA1:", h.SHA1)
+	}
+	if h.MD5 != "9e107d9d372bb6826bd81d3542a419d6" {
+		T.Error("Wrong MD5:", h.MD5)
+	}
+	if h.Ed2k != "1bee69a46ba811185c194762abaeae90" {
+		T.Error("Wrong ed2k:", h.Ed2k)
+	}
+}
+
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+	return len(p), nil
+}
+
+func TestReaderEd2kChunks(T *testing.T) {
+	T.Parallel()
+
+	// ed2k splits files in 9728000 byte chunks; files of exactly one chunk
+	// use the chunk's hash directly instead of appending an empty chunk.
+	for _, test := range []struct {
+		size int64
+		ed2k string
+	}{
+		{0, "31d6cfe0d16ae931b73c59d7e0c089c0"},
+		{9728000, "d7def262a127cd79096a108e7a9fc138"},
+		{9728001, "06329e9dba1373512c06386fe29e3c65"},
+	} {
+		h, err := Reader(io.LimitReader(zeroReader{}, test.size))
+		if err != nil {
+			T.Fatal(err)
+		}
+		if h.Size != test.size {
+			T.Error("Wrong size:", h.Size)
+		}
+		if h.Ed2k != test.ed2k {
+			T.Errorf("Wrong ed2k for %d bytes: %s", test.size, h.Ed2k)
+		}
+	}
+}
+
+func TestHashFiles(T *testing.T) {
+	T.Parallel()
+
+	dir, err := ioutil.TempDir("", "anidb-hash")
+	if err != nil {
+		T.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	paths := []string{}
+	for i, data := range []string{"", "a", strings.Repeat("b", 3<<20)} {
+		p := filepath.Join(dir, string('0'+rune(i)))
+		if err := ioutil.WriteFile(p, []byte(data), 0644); err != nil {
+			T.Fatal(err)
+		}
+		paths = append(paths, p)
+	}
+
+	cache, err := fscache.NewCacheDir(filepath.Join(dir, "cache"))
+	if err != nil {
+		T.Fatal(err)
+	}
+
+	progress := map[string]int64{}
+	progressLock := sync.Mutex{}
+	h := &Hasher{
+		MaxConcurrent: 2,
+		Cache:         cache,
+	}
+	h.Progress = func(path string, done, total int64) {
+		if done > total {
+			T.Error("Progress past the end of", path)
+		}
+		progressLock.Lock()
+		progress[path] = done
+		progressLock.Unlock()
+	}
+
+	results := map[string]*Hashes{}
+	for r := range h.HashFiles(paths...) {
+		if r.Err != nil {
+			T.Fatal(r.Err)
+		}
+		results[r.Path] = r.Hashes
+	}
+	if len(results) != len(paths) {
+		T.Fatal("Expected", len(paths), "results, got", len(results))
+	}
+	if h := results[paths[2]]; h.Size != 3<<20 {
+		T.Error("Wrong size:", h.Size)
+	}
+	for _, p := range paths {
+		if d, ok := progress[p]; !ok || d != results[p].Size {
+			T.Error("Final progress not reported for", p)
+		}
+	}
+	progress = map[string]int64{}
+
+	// now from the cache
+	h.Progress = func(path string, done, total int64) { progress[path] = done }
+	for _, p := range paths {
+		cached, err := h.HashFile(p)
+		if err != nil {
+			T.Fatal(err)
+		}
+		if *cached != *results[p] {
+			T.Error("Cached hashes differ for", p, "-", cached, results[p])
+		}
+		if progress[p] != cached.Size {
+			T.Error("Progress not reported for", p)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package hash
+
+import (
+	"github.com/Kovensky/go-fscache"
+	"os"
+	"path/filepath"
+)
+
+// Hashes local files, optionally caching the results.
+type Hasher struct {
+	// Called as files are read; may be nil. Can be called concurrently
+	// when hashing several files at once.
+	Progress ProgressFunc
+
+	// Maximum number of files read at once by HashFiles (default: 1).
+	// Hashing is usually limited by disk speed, so only raise this when
+	// the files are spread over several disks.
+	MaxConcurrent int
+
+	// Where results are cached, keyed by path, size, modification time
+	// and inode; may be nil.
+	Cache *fscache.CacheDir
+}
+
+// The result of hashing one of the files given to HashFiles.
+type Result struct {
+	Path   string
+	Hashes *Hashes
+	Err    error
+}
+
+func (h *Hasher) cacheKey(path string, stat os.FileInfo) []fscache.CacheKey {
+	return []fscache.CacheKey{"hashes", path, stat.Size(), stat.ModTime().UnixNano(), inode(stat)}
+}
+
+// Returns the cached hashes of the file at the given path, or nil if
+// there are none for its current size, modification time and inode.
+//
+// Since changes to a file's contents usually change its modification time,
+// these are the hashes from when the file was last written.
+func (h *Hasher) Cached(path string) *Hashes {
+	if h.Cache == nil {
+		return nil
+	}
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil
+	}
+	stat, err := os.Stat(path)
+	if err != nil {
+		return nil
+	}
+
+	var cached Hashes
+	if _, err := h.Cache.Get(&cached, h.cacheKey(path, stat)...); err == nil &&
+		cached.Size == stat.Size() && cached.Ed2k != "" {
+		return &cached
+	}
+	return nil
+}
+
+// Hashes the file at the given path, unless it's cached.
+func (h *Hasher) HashFile(path string) (*Hashes, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	fh, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+
+	stat, err := fh.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	if h.Cache != nil {
+		var cached Hashes
+		if _, err := h.Cache.Get(&cached, h.cacheKey(path, stat)...); err == nil &&
+			cached.Size == stat.Size() && cached.Ed2k != "" {
+			if h.Progress != nil {
+				h.Progress(path, cached.Size, cached.Size)
+			}
+			return &cached, nil
+		}
+	}
+
+	hashes, err := hashReader(fh, path, stat.Size(), h.Progress)
+	if err != nil {
+		return nil, err
+	}
+
+	if h.Cache != nil {
+		h.Cache.Set(hashes, h.cacheKey(path, stat)...)
+	}
+	return hashes, nil
+}
+
+// Hashes all of the given files, reading at most MaxConcurrent at once.
+//
+// Results are returned in no particular order.
+func (h *Hasher) HashFiles(paths ...string) <-chan *Result {
+	ch := make(chan *Result, len(paths))
+
+	max := h.MaxConcurrent
+	if max < 1 {
+		max = 1
+	}
+
+	go func() {
+		sem := make(chan bool, max)
+		done := make(chan bool)
+
+		for _, path := range paths {
+			sem <- true
+			go func(path string) {
+				hashes, err := h.HashFile(path)
+				ch <- &Result{Path: path, Hashes: hashes, Err: err}
+				<-sem
+				done <- true
+			}(path)
+		}
+		for _ = range paths {
+			<-done
+		}
+		close(ch)
+	}()
+	return ch
+}
+
+// Hashes the file at the given path, without caching or progress reports.
+func File(path string) (*Hashes, error) {
+	return (&Hasher{}).HashFile(path)
+}
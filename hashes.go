@@ -0,0 +1,11 @@
+package anidb
+
+import (
+	"github.com/Kovensky/go-anidb/hash"
+)
+
+// Hashes the file at the given path, caching the result in the library's
+// cache; a file is only read again if it changes.
+func HashFile(path string) (*hash.Hashes, error) {
+	return (&hash.Hasher{Cache: &Cache}).HashFile(path)
+}
@@ -0,0 +1,187 @@
+package anidb
+
+import (
+	"github.com/Kovensky/go-anidb/hash"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// File extensions considered by IdentifyTree by default.
+var VideoExtensions = []string{
+	".avi", ".flv", ".m2ts", ".m4v", ".mkv", ".mov", ".mp4", ".mpeg", ".mpg",
+	".ogm", ".rm", ".rmvb", ".ts", ".webm", ".wmv",
+}
+
+type IdentifyStatus int
+
+const (
+	IdentifyMatched = IdentifyStatus(1 + iota) // The file is known to AniDB.
+	IdentifyUnknown                            // The file isn't known to AniDB.
+	IdentifyError                              // The file couldn't be hashed, or the API failed.
+)
+
+func (s IdentifyStatus) String() string {
+	switch s {
+	case IdentifyMatched:
+		return "matched"
+	case IdentifyUnknown:
+		return "unknown"
+	case IdentifyError:
+		return "error"
+	default:
+		return "invalid"
+	}
+}
+
+// The result of identifying a local file.
+type IdentifyResult struct {
+	Path   string
+	Hashes *hash.Hashes // nil if the file couldn't be hashed
+	File   *File        // nil unless Status is IdentifyMatched
+
+	Status IdentifyStatus
+	Err    error // set if the file couldn't be hashed
+}
+
+type IdentifyOptions struct {
+	// Only files with these extensions are identified; matched
+	// case-insensitively. Defaults to VideoExtensions.
+	Extensions []string
+
+	// Maximum number of files hashed at once (default: 1).
+	MaxHashing int
+
+	// Called as files are hashed; may be nil.
+	Progress hash.ProgressFunc
+
+	// Chooses between files with colliding hashes.
+	// Defaults to PreferNonDeprecated.
+	Pick FileDisambiguator
+}
+
+// Hashes the file at the given path and retrieves the matching File.
+// Hashes are cached, see HashFile. Uses the UDP API.
+//
+// Returns nil if the file can't be read, isn't known, or on API error.
+func (adb *AniDB) FileByPath(path string) <-chan *File {
+	ch := make(chan *File, 1)
+
+	go func() {
+		if h, err := HashFile(path); err == nil {
+			ch <- <-adb.FileByEd2kSize(h.Ed2k, h.Size)
+		} else {
+			ch <- nil
+		}
+		close(ch)
+	}()
+	return ch
+}
+
+// Identifies the hashed file, filling the result's File and Status.
+func (adb *AniDB) identify(r *IdentifyResult, pick FileDisambiguator) {
+	files := []*File{}
+	apiError := false
+	for f := range adb.FilesByEd2kSize(r.Hashes.Ed2k, r.Hashes.Size) {
+		if f == nil {
+			apiError = true
+		} else {
+			files = append(files, f)
+		}
+	}
+
+	switch {
+	case len(files) == 1:
+		r.File = files[0]
+	case len(files) > 1:
+		r.File = pick(files)
+	}
+
+	switch {
+	case r.File != nil:
+		r.Status = IdentifyMatched
+	case apiError:
+		r.Status = IdentifyError
+	default:
+		r.Status = IdentifyUnknown
+	}
+}
+
+func hasExtension(path string, exts []string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, e := range exts {
+		if strings.ToLower(e) == ext {
+			return true
+		}
+	}
+	return false
+}
+
+// Walks the directory tree under root, and identifies every file with one
+// of the configured extensions. Hashes are cached, see HashFile; lookups go
+// through the regular UDP API queue, so they respect its throttling.
+// Files that weren't known to AniDB are only looked up again after
+// InvalidKeyCacheDuration.
+//
+// Results are returned as they become available, in no particular order.
+// If opts is nil, the defaults are used.
+func (adb *AniDB) IdentifyTree(root string, opts *IdentifyOptions) <-chan *IdentifyResult {
+	ch := make(chan *IdentifyResult, 10)
+
+	o := IdentifyOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	if o.Extensions == nil {
+		o.Extensions = VideoExtensions
+	}
+	if o.MaxHashing < 1 {
+		o.MaxHashing = 1
+	}
+	if o.Pick == nil {
+		o.Pick = PreferNonDeprecated
+	}
+
+	hasher := &hash.Hasher{Progress: o.Progress, Cache: &Cache}
+
+	go func() {
+		sem := make(chan bool, o.MaxHashing)
+		wg := sync.WaitGroup{}
+
+		filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			switch {
+			case err != nil:
+				ch <- &IdentifyResult{Path: path, Status: IdentifyError, Err: err}
+				if info != nil && info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			case !info.Mode().IsRegular() || !hasExtension(path, o.Extensions):
+				return nil
+			}
+
+			sem <- true
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				r := &IdentifyResult{Path: path}
+				r.Hashes, r.Err = hasher.HashFile(path)
+				<-sem
+
+				if r.Err != nil {
+					r.Status = IdentifyError
+				} else {
+					adb.identify(r, o.Pick)
+				}
+				ch <- r
+			}()
+			return nil
+		})
+
+		wg.Wait()
+		close(ch)
+	}()
+	return ch
+}
@@ -0,0 +1,156 @@
+package anidb
+
+import (
+	"github.com/Kovensky/go-anidb/release"
+	"os"
+	"strings"
+)
+
+// The result of identifying a file by its release name.
+type NameMatch struct {
+	Info *release.Info // What was parsed from the name
+
+	AID  AID
+	GID  GID
+	EID  EID
+	File *File // nil unless a single file matched
+
+	// How likely it is that the match is right, from 0 to 1.
+	// Only a match of the CRC32 or of the file size gets close to 1.
+	Confidence float64
+}
+
+// Weights of each identified part in NameMatch.Confidence.
+const (
+	nameAnimeWeight   = 0.25
+	nameGroupWeight   = 0.15
+	nameEpisodeWeight = 0.15
+	nameFileWeight    = 0.45
+)
+
+// Picks the anime whose title is the parsed title, or else the one with the
+// smallest AID. The score is lower the more ambiguous the search was.
+func pickAnimeByTitle(title string) (aid AID, score float64) {
+	results := SearchAnimeFoldAll(title).ResultsByAID()
+	if len(results) == 0 {
+		return 0, 0
+	}
+
+	for _, a := range results {
+		names := []string{a.PrimaryTitle}
+		for _, ns := range a.OfficialNames {
+			for _, n := range ns {
+				names = append(names, n.Title)
+			}
+		}
+		for _, ns := range a.Synonyms {
+			for _, n := range ns {
+				names = append(names, n.Title)
+			}
+		}
+		for _, n := range names {
+			if strings.EqualFold(n, title) {
+				return AID(a.AID), 1
+			}
+		}
+	}
+	return AID(results[0].AID), 0.5 / float64(len(results))
+}
+
+// Identifies a file from its name alone, without reading it: the name is
+// parsed with release.Parse, the title is searched in the titles database,
+// the group with GroupByName, and the file among the group's files for the
+// episode (see FIDsByGID). If the file exists, its size is compared as well.
+//
+// The returned NameMatch contains as much as could be identified.
+// Uses the UDP API.
+func (adb *AniDB) FileByName(path string) <-chan *NameMatch {
+	ch := make(chan *NameMatch, 1)
+
+	go func() {
+		m := &NameMatch{Info: release.Parse(path)}
+		adb.identifyName(m, path)
+		ch <- m
+		close(ch)
+	}()
+	return ch
+}
+
+func (adb *AniDB) identifyName(m *NameMatch, path string) {
+	info := m.Info
+
+	aid, score := pickAnimeByTitle(info.Title)
+	if aid < 1 {
+		return
+	}
+	m.AID = aid
+	m.Confidence += nameAnimeWeight * score
+
+	gch := adb.GroupByName(info.Group)
+	a := <-adb.AnimeByID(aid)
+	if g := <-gch; g != nil {
+		m.GID = g.GID
+		m.Confidence += nameGroupWeight
+	}
+
+	if info.Episode == nil {
+		return
+	}
+	eps := a.EpisodeList(info.Episode)
+	if len(eps) == 0 {
+		return
+	}
+	ep := eps[0]
+	m.EID = ep.EID
+	m.Confidence += nameEpisodeWeight
+
+	if m.GID < 1 {
+		return
+	}
+
+	size := int64(-1)
+	if stat, err := os.Stat(path); err == nil {
+		size = stat.Size()
+	}
+
+	candidates := []*File{}
+	for f := range adb.FilesByGID(ep, m.GID) {
+		if f == nil {
+			continue
+		}
+		switch {
+		case info.CRC32 != "" && f.CRC32 != "" && info.CRC32 != f.CRC32,
+			size >= 0 && f.Filesize > 0 && size != f.Filesize:
+			continue
+		case info.CRC32 != "" && info.CRC32 == f.CRC32,
+			size >= 0 && size == f.Filesize:
+			// conclusive
+			m.File = f
+			m.Confidence += nameFileWeight
+			return
+		}
+		candidates = append(candidates, f)
+	}
+
+	// narrow down with the weaker hints
+	version := FileVersion(info.Version)
+	if version < 1 {
+		version = 1
+	}
+	filtered := []*File{}
+	for _, f := range candidates {
+		if f.Version != version {
+			continue
+		}
+		if info.Resolution > 0 && f.VideoInfo.Resolution.Dy() > 0 &&
+			f.VideoInfo.Resolution.Dy() != info.Resolution {
+			continue
+		}
+		filtered = append(filtered, f)
+	}
+
+	if len(filtered) == 1 {
+		m.File = filtered[0]
+		m.Confidence += nameFileWeight * 0.5
+	}
+}
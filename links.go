@@ -0,0 +1,123 @@
+package anidb
+
+import (
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Returns an ed2k:// link for the File, with the given file name.
+// Returns an empty string if the File has no ed2k hash.
+func (f *File) Ed2kLink(name string) string {
+	if f == nil || f.Ed2kHash == "" || f.Filesize < 1 {
+		return ""
+	}
+	return fmt.Sprintf("ed2k://|file|%s|%d|%s|/", escapeLinkName(name), f.Filesize, strings.ToLower(f.Ed2kHash))
+}
+
+// Returns a magnet: link for the File, with the given file name.
+// Includes the SHA1 hash when known. Returns an empty string if the File
+// has no ed2k hash.
+func (f *File) MagnetLink(name string) string {
+	if f == nil || f.Ed2kHash == "" || f.Filesize < 1 {
+		return ""
+	}
+
+	parts := []string{"xt=urn:ed2k:" + strings.ToLower(f.Ed2kHash)}
+	if sha1, err := hex.DecodeString(f.SHA1Hash); err == nil && len(sha1) == 20 {
+		parts = append(parts, "xt=urn:sha1:"+base32.StdEncoding.EncodeToString(sha1))
+	}
+	parts = append(parts, "xl="+strconv.FormatInt(f.Filesize, 10))
+	if name != "" {
+		parts = append(parts, "dn="+url.QueryEscape(name))
+	}
+	return "magnet:?" + strings.Join(parts, "&")
+}
+
+// ed2k links use | as separator, so it has to be escaped along with the
+// usual characters.
+func escapeLinkName(name string) string {
+	return strings.Replace(url.QueryEscape(name), "+", "%20", -1)
+}
+
+// The file identified by an ed2k:// or magnet: link.
+type FileLink struct {
+	Name     string
+	Ed2kHash string // Lowercase
+	Filesize int64
+}
+
+var InvalidLinkError = errors.New("anidb: not a valid ed2k or magnet link")
+
+// Parses an ed2k:// file link or a magnet: link with an ed2k hash and size.
+func ParseFileLink(link string) (*FileLink, error) {
+	var l *FileLink
+	switch {
+	case strings.HasPrefix(link, "ed2k://"):
+		l = parseEd2kLink(link)
+	case strings.HasPrefix(link, "magnet:?"):
+		l = parseMagnetLink(link)
+	}
+	if l == nil || l.Filesize < 1 || !validEd2kHash.MatchString(l.Ed2kHash) {
+		return nil, InvalidLinkError
+	}
+	l.Ed2kHash = strings.ToLower(l.Ed2kHash)
+	return l, nil
+}
+
+// ed2k://|file|<name>|<size>|<hash>|[optional fields|]/
+func parseEd2kLink(link string) *FileLink {
+	parts := strings.Split(strings.TrimPrefix(link, "ed2k://"), "|")
+	if len(parts) < 6 || parts[0] != "" || parts[1] != "file" {
+		return nil
+	}
+
+	name, err := url.PathUnescape(parts[2])
+	if err != nil {
+		name = parts[2]
+	}
+	size, err := strconv.ParseInt(parts[3], 10, 64)
+	if err != nil {
+		return nil
+	}
+	return &FileLink{Name: name, Ed2kHash: parts[4], Filesize: size}
+}
+
+func parseMagnetLink(link string) *FileLink {
+	q, err := url.ParseQuery(strings.TrimPrefix(link, "magnet:?"))
+	if err != nil {
+		return nil
+	}
+
+	l := &FileLink{Name: q.Get("dn")}
+	for _, xt := range q["xt"] {
+		for _, prefix := range []string{"urn:ed2k:", "urn:ed2khash:"} {
+			if strings.HasPrefix(xt, prefix) {
+				l.Ed2kHash = strings.TrimPrefix(xt, prefix)
+			}
+		}
+	}
+	if l.Filesize, err = strconv.ParseInt(q.Get("xl"), 10, 64); err != nil {
+		return nil
+	}
+	return l
+}
+
+// Parses the ed2k:// or magnet: link and retrieves the matching File.
+// Uses the UDP API.
+//
+// Returns nil if the link is invalid, the file isn't known, or on API error.
+func (adb *AniDB) FileByLink(link string) <-chan *File {
+	l, err := ParseFileLink(link)
+	if err != nil {
+		ch := make(chan *File, 1)
+		ch <- nil
+		close(ch)
+		return ch
+	}
+	return adb.FileByEd2kSize(l.Ed2kHash, l.Filesize)
+}
@@ -0,0 +1,58 @@
+package anidb
+
+import (
+	"testing"
+)
+
+func TestParseFileLink(T *testing.T) {
+	T.Parallel()
+
+	const hash = "0123456789abcdef0123456789abcdef"
+
+	for _, test := range []struct {
+		link string
+		exp  *FileLink // nil if the link is invalid
+	}{
+		{"ed2k://|file|[Group]%20A%7CB%20-%2001.mkv|1024|" + hash + "|/",
+			&FileLink{Name: "[Group] A|B - 01.mkv", Ed2kHash: hash, Filesize: 1024}},
+		{"ed2k://|file|a.mkv|1024|0123456789ABCDEF0123456789ABCDEF|h=ABCD|/",
+			&FileLink{Name: "a.mkv", Ed2kHash: hash, Filesize: 1024}},
+		{"ed2k://|file|a.mkv|big|" + hash + "|/", nil},
+		{"ed2k://|server|1.2.3.4|4661|/", nil},
+		{"magnet:?xt=urn:ed2k:" + hash + "&xl=1024&dn=a.mkv",
+			&FileLink{Name: "a.mkv", Ed2kHash: hash, Filesize: 1024}},
+		{"magnet:?xt=urn:ed2khash:0123456789ABCDEF0123456789ABCDEF&xl=1024",
+			&FileLink{Ed2kHash: hash, Filesize: 1024}},
+		{"magnet:?xt=urn:ed2k:" + hash + "&dn=a.mkv", nil},
+		{"magnet:?xt=urn:btih:c12fe1c06bba254a9dc9f519b335aa7c1367a88a&xl=1024", nil},
+		{"http://example.com/a.mkv", nil},
+	} {
+		l, err := ParseFileLink(test.link)
+		switch {
+		case test.exp == nil && err != InvalidLinkError:
+			T.Errorf("%s: expected InvalidLinkError, got %v, %v", test.link, l, err)
+		case test.exp != nil && err != nil:
+			T.Errorf("%s: %v", test.link, err)
+		case test.exp != nil && *l != *test.exp:
+			T.Errorf("%s: expected %v, got %v", test.link, *test.exp, *l)
+		}
+	}
+}
+
+func TestFileLinkRoundTrip(T *testing.T) {
+	T.Parallel()
+
+	f := &File{Filesize: 1024, Ed2kHash: "0123456789ABCDEF0123456789ABCDEF"}
+	name := "[Group] A|B & C - 01 (100%).mkv"
+
+	for _, link := range []string{f.Ed2kLink(name), f.MagnetLink(name)} {
+		l, err := ParseFileLink(link)
+		if err != nil {
+			T.Fatalf("%s: %v", link, err)
+		}
+		exp := FileLink{Name: name, Ed2kHash: "0123456789abcdef0123456789abcdef", Filesize: 1024}
+		if *l != exp {
+			T.Errorf("%s: expected %v, got %v", link, exp, *l)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package anidb
+
+import (
+	"github.com/Kovensky/go-anidb/misc"
+	"sync"
+)
+
+// Selects the mylist entries affected by a bulk operation.
+type MyListSelector struct {
+	AID AID // The anime whose entries are selected; required
+	GID GID // If not 0, only entries for files from this group are selected
+
+	// If not empty, only entries for files that contain at least one
+	// of these episodes are selected.
+	Episodes misc.EpisodeList
+}
+
+func (sel *MyListSelector) matches(e *MyListEntry, f *File) bool {
+	if sel.GID > 0 && e.GID != sel.GID {
+		return false
+	}
+	if len(sel.Episodes) == 0 {
+		return true
+	}
+	if f == nil {
+		return false
+	}
+	found := false
+	for ep := range f.EpisodeNumber.Episodes() {
+		// keep draining the channel
+		found = found || sel.Episodes.ContainsEpisodes(&ep)
+	}
+	return found
+}
+
+type MyListBulkOptions struct {
+	// Only report what would be changed, without sending any edits.
+	// Files are only looked up in the cache; entries whose File isn't
+	// cached are only selected if the selector doesn't filter by episode.
+	DryRun bool
+
+	// Maximum number of edits in flight at any given time (default: 1).
+	MaxConcurrent int
+}
+
+// See the constants list for valid values.
+type MyListBulkStatus int
+
+const (
+	MyListBulkUnchanged    = MyListBulkStatus(iota) // The entry already matched the MyListSet
+	MyListBulkDryRun                                // The entry would have been edited
+	MyListBulkEdited                                // The entry was edited
+	MyListBulkFailed                                // The edit failed
+	MyListBulkLookupFailed                          // The anime's mylist entries couldn't be retrieved
+)
+
+func (s MyListBulkStatus) String() string {
+	switch s {
+	case MyListBulkUnchanged:
+		return "Unchanged"
+	case MyListBulkDryRun:
+		return "Dry Run"
+	case MyListBulkEdited:
+		return "Edited"
+	case MyListBulkFailed:
+		return "Failed"
+	case MyListBulkLookupFailed:
+		return "Lookup Failed"
+	default:
+		return "Unknown"
+	}
+}
+
+type MyListBulkResult struct {
+	Entry *MyListEntry // The entry as it was before the edit; nil if the lookup failed
+	File  *File
+
+	Status MyListBulkStatus
+}
+
+// Returns whether applying the MyListSet would change the entry.
+func (set *MyListSet) changes(e *MyListEntry) bool {
+	switch {
+	case set == nil, e == nil:
+		return false
+	case set.State != nil && *set.State != e.MyListState:
+		return true
+	case set.ViewDate != nil && set.ViewDate.Unix() != e.DateWatched.Unix():
+		return true
+	case set.ViewDate == nil && set.Watched != nil && *set.Watched == e.DateWatched.IsZero():
+		return true
+	case set.Source != nil && *set.Source != e.Source:
+		return true
+	case set.Storage != nil && *set.Storage != e.Storage:
+		return true
+	case set.Other != nil && *set.Other != e.Other:
+		return true
+	}
+	return false
+}
+
+// Applies the MyListSet to every mylist entry matched by the selector,
+// using MyListEdit. Entries that already match the MyListSet are not edited.
+// The entries are found with MyListEntriesForAnime.
+//
+// The edits are sent with low priority: they're only sent when no other
+// query is waiting to be sent.
+//
+// The returned channel sends one result per selected entry. If the entries
+// couldn't all be retrieved, a result with the MyListBulkLookupFailed status
+// is also sent, and only the entries that were retrieved are edited.
+// If opts is nil, the default options are used.
+func (adb *AniDB) MyListEditBulk(sel MyListSelector, set *MyListSet, opts *MyListBulkOptions) <-chan *MyListBulkResult {
+	ch := make(chan *MyListBulkResult, 10)
+
+	if sel.AID < 1 {
+		close(ch)
+		return ch
+	}
+	if opts == nil {
+		opts = &MyListBulkOptions{}
+	}
+	max := opts.MaxConcurrent
+	if max < 1 {
+		max = 1
+	}
+
+	go func() {
+		type pending struct {
+			entry *MyListEntry
+			file  <-chan *File
+		}
+		list := []pending{}
+		for e := range adb.MyListEntriesForAnime(sel.AID) {
+			if e == nil {
+				// API error; the entries that follow, if any, are incomplete
+				ch <- &MyListBulkResult{Status: MyListBulkLookupFailed}
+				continue
+			}
+			var fc <-chan *File
+			if opts.DryRun {
+				c := make(chan *File, 1)
+				c <- e.FID.File()
+				fc = c
+			} else {
+				fc = adb.FileByID(e.FID)
+			}
+			list = append(list, pending{entry: e, file: fc})
+		}
+
+		sem := make(chan bool, max)
+		wg := sync.WaitGroup{}
+
+		for _, p := range list {
+			f := <-p.file
+			if !sel.matches(p.entry, f) {
+				continue
+			}
+
+			r := &MyListBulkResult{Entry: p.entry, File: f}
+			switch {
+			case !set.changes(p.entry):
+				r.Status = MyListBulkUnchanged
+			case opts.DryRun:
+				r.Status = MyListBulkDryRun
+			case f == nil:
+				r.Status = MyListBulkFailed
+			default:
+				sem <- true
+				wg.Add(1)
+				go func(r *MyListBulkResult) {
+					defer wg.Done()
+
+					if <-adb.myListEditAsync(r.File, set, true) {
+						r.Status = MyListBulkEdited
+					} else {
+						r.Status = MyListBulkFailed
+					}
+					ch <- r
+					<-sem
+				}(r)
+				continue
+			}
+			ch <- r
+		}
+
+		wg.Wait()
+		close(ch)
+	}()
+	return ch
+}
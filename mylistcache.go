@@ -260,9 +260,137 @@ func (adb *AniDB) parseMylistReply(reply udpapi.APIReply) *MyListEntry {
 		}
 
 		CacheSet(e, "mylist", "by-fid", e.FID, user.UID)
+
+		mirrorMyListEntry(user.UID, e.AID, e.LID)
 	}
 
 	CacheSet(e, "mylist", e.LID)
 
 	return e
 }
+
+// Retrieves every MyListEntry the current user has for the given Anime.
+// The returned channel may return multiple (or no) entries. Uses the UDP API.
+//
+// The entries are found by combining the MyListAnime summary with the Files
+// each Group released for each of the listed episodes, so entries for files
+// that aren't associated with a group aren't found.
+//
+// On API error (offline, etc), the first *MyListEntry returned is nil,
+// followed by the entries that could be found.
+func (adb *AniDB) MyListEntriesForAnime(aid AID) <-chan *MyListEntry {
+	ch := make(chan *MyListEntry, 10)
+
+	if aid < 1 {
+		ch <- nil
+		close(ch)
+		return ch
+	}
+
+	go func() {
+		user := <-adb.GetCurrentUser()
+		if user == nil || user.UID < 1 {
+			ch <- nil
+			close(ch)
+			return
+		}
+
+		key := []fscache.CacheKey{"mylist-entries", user.UID, aid}
+
+		ic := make(chan notification, 1)
+		go func() {
+			for c := range ic {
+				ch <- c.(*MyListEntry)
+			}
+			close(ch)
+		}()
+		if intentMap.Intent(ic, key...) {
+			return
+		}
+
+		var lids []LID
+		switch ts, err := Cache.Get(&lids, key...); {
+		case err == nil && time.Now().Sub(ts) < MyListCacheDuration:
+			entryChans := make([]<-chan *MyListEntry, len(lids))
+			for i, lid := range lids {
+				entryChans[i] = adb.MyListByLID(lid)
+			}
+			entries := make([]*MyListEntry, 0, len(lids))
+			for _, c := range entryChans {
+				if e := <-c; e != nil {
+					entries = append(entries, e)
+				}
+			}
+
+			is := intentMap.LockIntent(key...)
+			defer intentMap.Free(is, key...)
+			defer is.Close()
+
+			for _, e := range entries {
+				is.Notify(e)
+			}
+			return
+		}
+
+		apiError := false
+
+		mlaChan := adb.MyListAnime(aid)
+		a := <-adb.AnimeByID(aid)
+		mla := <-mlaChan
+
+		switch {
+		case mla == nil && !Cache.IsValid(InvalidKeyCacheDuration, "mylist-anime", user.UID, aid):
+			// not in mylist
+			mla = &MyListAnime{AID: aid}
+		case mla == nil, a == nil:
+			apiError = true
+			mla = &MyListAnime{AID: aid}
+		}
+
+		fidChans := []<-chan FID{}
+		for gid, el := range mla.EpisodesPerGroup {
+			for _, ep := range a.EpisodeList(el) {
+				fidChans = append(fidChans, adb.FIDsByGID(ep, gid))
+			}
+		}
+
+		seenFID := map[FID]bool{}
+		entryChans := []<-chan *MyListEntry{}
+		for _, c := range fidChans {
+			for fid := range c {
+				if fid == 0 {
+					apiError = true
+					continue
+				}
+				if !seenFID[fid] {
+					seenFID[fid] = true
+					entryChans = append(entryChans, adb.MyListByFID(fid))
+				}
+			}
+		}
+
+		entries := make([]*MyListEntry, 0, len(entryChans))
+		lids = make([]LID, 0, len(entryChans))
+		for _, c := range entryChans {
+			if e := <-c; e != nil {
+				entries = append(entries, e)
+				lids = append(lids, e.LID)
+			}
+		}
+
+		is := intentMap.LockIntent(key...)
+		defer intentMap.Free(is, key...)
+
+		if apiError {
+			is.Notify((*MyListEntry)(nil))
+		} else {
+			CacheSet(&lids, key...)
+		}
+
+		defer is.Close()
+		for _, e := range entries {
+			is.Notify(e)
+		}
+	}()
+	return ch
+}
@@ -0,0 +1,204 @@
+package anidb
+
+import (
+	"errors"
+	"github.com/Kovensky/go-anidb/misc"
+	"github.com/Kovensky/go-anidb/mylistexport"
+	"strings"
+	"time"
+)
+
+// Schedules an export of the current user's mylist, using the named export
+// template. AniDB makes the archive available for download once it's ready;
+// use ImportMyListExport to load it.
+//
+// Returns whether the export was queued (or already was). Uses the UDP API.
+func (adb *AniDB) RequestMyListExport(template string) <-chan bool {
+	ch := make(chan bool, 1)
+
+	if template == "" {
+		ch <- false
+		close(ch)
+		return ch
+	}
+
+	go func() {
+		reply := <-adb.udp.SendRecv("MYLISTEXPORT", paramMap{"template": template})
+
+		switch reply.Code() {
+		case 217, 318: // export queued, already in queue
+			ch <- true
+		default:
+			ch <- false
+		}
+		close(ch)
+	}()
+	return ch
+}
+
+// Cancels a scheduled mylist export.
+//
+// Returns whether an export was cancelled. Uses the UDP API.
+func (adb *AniDB) CancelMyListExport() <-chan bool {
+	ch := make(chan bool, 1)
+
+	go func() {
+		reply := <-adb.udp.SendRecv("MYLISTEXPORT", paramMap{"cancel": 1})
+
+		ch <- reply.Code() == 218
+		close(ch)
+	}()
+	return ch
+}
+
+var NoUserError = errors.New("Unknown mylist user")
+
+// Imports the mylist export archive (or XML file) at the given path into the
+// cache, and adds its entries to the user's MyListMirror.
+// See the mylistexport package for the supported formats.
+func (adb *AniDB) ImportMyListExport(name string) error {
+	ml, err := mylistexport.Open(name)
+	if err != nil {
+		return err
+	}
+	return adb.ImportMyList(ml)
+}
+
+// Imports an already parsed mylist export into the cache, and adds its
+// entries to the user's MyListMirror.
+//
+// Files that aren't cached yet are cached with the (incomplete) data
+// from the export.
+func (adb *AniDB) ImportMyList(ml *mylistexport.MyList) error {
+	uid := UID(ml.User.ID)
+	if uid < 1 {
+		if user := adb.User(); user != nil {
+			uid = user.UID
+		}
+	}
+	if uid < 1 {
+		return NoUserError
+	}
+
+	mirror := &MyListMirror{adb: adb, UID: uid}
+
+	for _, a := range ml.Anime {
+		aid := AID(a.ID)
+		mla := &MyListAnime{
+			AID: aid,
+
+			EpisodesWithState: MyListStateMap{},
+			EpisodesPerGroup:  GroupEpisodes{},
+		}
+
+		entries := []*MyListEntry{}
+		lids := []LID{}
+
+		for _, ep := range a.Episodes {
+			epno := misc.ParseEpisodeList(ep.EpNo)
+
+			for _, ef := range ep.Files {
+				e := &MyListEntry{
+					LID: LID(ef.LID),
+
+					FID: FID(ef.ID),
+					EID: EID(ep.ID),
+					AID: aid,
+					GID: GID(ef.GroupID),
+
+					DateAdded:   exportTime(ef.Added),
+					DateWatched: exportTime(ef.Viewed),
+
+					State:       FileState(ef.FileState),
+					MyListState: MyListState(ef.State),
+
+					Storage: ef.Storage,
+					Source:  ef.Source,
+					Other:   ef.Other,
+				}
+				entries = append(entries, e)
+				lids = append(lids, e.LID)
+
+				importExportFile(uid, aid, &ep, &ef, epno)
+				CacheSet(e.LID, "mylist", "by-fid", e.FID, uid)
+
+				el := mla.EpisodesWithState[e.MyListState]
+				el.Add(epno)
+				mla.EpisodesWithState[e.MyListState] = el
+
+				if !e.DateWatched.IsZero() {
+					mla.WatchedEpisodes.Add(epno)
+				}
+
+				if e.GID > 0 {
+					eg := mla.EpisodesPerGroup[e.GID]
+					eg.Add(epno)
+					mla.EpisodesPerGroup[e.GID] = eg
+				}
+			}
+		}
+
+		CacheSet(mla, "mylist-anime", uid, aid)
+		// unlike MyListEntriesForAnime's, this list includes generic files
+		CacheSet(&lids, "mylist-entries", uid, aid)
+		mirror.Import(entries...)
+	}
+
+	return nil
+}
+
+func exportTime(ts int64) time.Time {
+	if ts == 0 {
+		return time.Time{}
+	}
+	return time.Unix(ts, 0)
+}
+
+// Caches the file from the export, unless there's already a cached version.
+func importExportFile(uid UID, aid AID, ep *mylistexport.Episode, ef *mylistexport.File, epno misc.EpisodeList) {
+	fid := FID(ef.ID)
+
+	if f := fid.File(); f != nil {
+		if f.LID[uid] != LID(ef.LID) {
+			f.LID[uid] = LID(ef.LID)
+			Cache.Set(f, "fid", f.FID)
+			Cache.Chtime(f.Cached, "fid", f.FID)
+		}
+		return
+	}
+
+	version := FileVersion(ef.Version)
+	if version < 1 {
+		version = 1
+	}
+
+	f := &File{
+		FID: fid,
+
+		AID: aid,
+		EID: EID(ep.ID),
+		GID: GID(ef.GroupID),
+		LID: LIDMap{uid: LID(ef.LID)},
+
+		EpisodeString: epno.String(),
+		EpisodeNumber: epno,
+
+		// the export lacks most of the data
+		Incomplete: true,
+
+		Deprecated: ef.Deprecated,
+		Version:    version,
+
+		Filesize: ef.Size,
+		Ed2kHash: strings.ToLower(ef.Ed2k),
+		SHA1Hash: strings.ToLower(ef.SHA1),
+		CRC32:    strings.ToLower(ef.CRC),
+	}
+
+	if f.Ed2kHash != "" && f.Filesize > 0 {
+		cacheFile(f)
+	} else {
+		CacheSet(f.AID, "aid", "by-eid", f.EID)
+		CacheSet(f, "fid", f.FID)
+	}
+}
@@ -0,0 +1,147 @@
+// Parses the archives generated by the MYLISTEXPORT UDP API command.
+//
+// Only the xml-plain-cs template is understood. The archive is a gzipped
+// tarball with a single XML file, shaped like:
+//
+//	<MyList>
+//	  <User Id="1" Name="user" />
+//	  <Anime Id="1" Name="Seikai no Monshou" EpCount="13">
+//	    <Ep Id="1" EpNo="1" Name="Invasion">
+//	      <File Id="1" LId="2" GroupId="3" Ed2k="..." Size="..." CRC="..." SHA1="..."
+//	            Version="1" Deprecated="0" State="1" FileState="0" Storage="" Source=""
+//	            Other="" Added="1230000000" Viewed="1230000000" />
+//	    </Ep>
+//	  </Anime>
+//	</MyList>
+//
+// Timestamps are in Unix time; a Viewed of 0 means the file wasn't watched.
+//
+// http://wiki.anidb.info/w/UDP_API_Definition#MYLISTEXPORT:_Schedule_a_MyList_Export
+package mylistexport
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/xml"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+type User struct {
+	ID   int    `xml:"Id,attr"`
+	Name string `xml:"Name,attr"`
+}
+
+type File struct {
+	ID      int `xml:"Id,attr"`      // FID
+	LID     int `xml:"LId,attr"`     // MyList ID
+	GroupID int `xml:"GroupId,attr"` // GID; 0 for generic files
+
+	Ed2k string `xml:"Ed2k,attr"`
+	Size int64  `xml:"Size,attr"`
+	CRC  string `xml:"CRC,attr"`
+	SHA1 string `xml:"SHA1,attr"`
+
+	Version    int  `xml:"Version,attr"`
+	Deprecated bool `xml:"Deprecated,attr"`
+
+	State     int    `xml:"State,attr"`     // MyList state (HDD, CD, deleted...)
+	FileState int    `xml:"FileState,attr"` // File state (original, corrupted...)
+	Storage   string `xml:"Storage,attr"`
+	Source    string `xml:"Source,attr"`
+	Other     string `xml:"Other,attr"`
+
+	Added  int64 `xml:"Added,attr"`  // Unix time
+	Viewed int64 `xml:"Viewed,attr"` // Unix time; 0 if not watched
+}
+
+type Episode struct {
+	ID   int    `xml:"Id,attr"`   // EID
+	EpNo string `xml:"EpNo,attr"` // In the usual AniDB API format
+	Name string `xml:"Name,attr"`
+
+	Files []File `xml:"File"`
+}
+
+type Anime struct {
+	ID      int    `xml:"Id,attr"` // AID
+	Name    string `xml:"Name,attr"`
+	EpCount int    `xml:"EpCount,attr"`
+
+	Episodes []Episode `xml:"Ep"`
+}
+
+type MyList struct {
+	User  User    `xml:"User"`
+	Anime []Anime `xml:"Anime"`
+}
+
+var NoXMLError = errors.New("No XML file found in the export archive")
+
+// Parses the XML file inside an export archive.
+func ParseXML(r io.Reader) (*MyList, error) {
+	ml := &MyList{}
+	if err := xml.NewDecoder(r).Decode(ml); err != nil {
+		return nil, err
+	}
+	return ml, nil
+}
+
+// Parses an export archive. The reader may also be the (possibly gzipped)
+// XML file itself.
+func Parse(r io.Reader) (*MyList, error) {
+	br := bufio.NewReader(r)
+	if magic, err := br.Peek(2); err == nil && bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		br = bufio.NewReader(gz)
+	}
+
+	// skip whitespace to find out whether this is XML or a tarball
+	for {
+		b, err := br.Peek(1)
+		if err != nil {
+			return nil, err
+		}
+		if !strings.ContainsRune(" \t\r\n", rune(b[0])) {
+			break
+		}
+		br.ReadByte()
+	}
+
+	if b, _ := br.Peek(1); b[0] == '<' {
+		return ParseXML(br)
+	}
+
+	tr := tar.NewReader(br)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil, NoXMLError
+		} else if err != nil {
+			return nil, err
+		}
+		if strings.EqualFold(path.Ext(hdr.Name), ".xml") {
+			return ParseXML(tr)
+		}
+	}
+}
+
+// Opens and parses the export archive (or XML file) at the given path.
+func Open(name string) (*MyList, error) {
+	fh, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+
+	return Parse(fh)
+}
@@ -0,0 +1,99 @@
+package mylistexport_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"github.com/Kovensky/go-anidb/mylistexport"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const sampleXML = `<?xml version="1.0" encoding="UTF-8"?>
+<MyList>
+  <User Id="42" Name="user" />
+  <Anime Id="1" Name="Seikai no Monshou" EpCount="13">
+    <Ep Id="1" EpNo="1" Name="Invasion">
+      <File Id="10" LId="100" GroupId="3" Ed2k="0123456789abcdef0123456789abcdef" Size="1024"
+            CRC="abcd1234" Version="2" Deprecated="0" State="1" FileState="0"
+            Storage="disk1" Added="1230000000" Viewed="1230000500" />
+    </Ep>
+    <Ep Id="2" EpNo="S1" Name="Special">
+      <File Id="11" LId="101" GroupId="0" State="2" Added="1230000000" Viewed="0" />
+    </Ep>
+  </Anime>
+</MyList>
+`
+
+var sampleList = &mylistexport.MyList{
+	User: mylistexport.User{ID: 42, Name: "user"},
+	Anime: []mylistexport.Anime{{
+		ID: 1, Name: "Seikai no Monshou", EpCount: 13,
+		Episodes: []mylistexport.Episode{
+			{ID: 1, EpNo: "1", Name: "Invasion", Files: []mylistexport.File{{
+				ID: 10, LID: 100, GroupID: 3,
+				Ed2k: "0123456789abcdef0123456789abcdef", Size: 1024, CRC: "abcd1234",
+				Version: 2, State: 1, Storage: "disk1",
+				Added: 1230000000, Viewed: 1230000500,
+			}}},
+			{ID: 2, EpNo: "S1", Name: "Special", Files: []mylistexport.File{{
+				ID: 11, LID: 101, State: 2, Added: 1230000000,
+			}}},
+		},
+	}},
+}
+
+func makeArchive(T *testing.T) []byte {
+	buf := bytes.Buffer{}
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+
+	files := []struct{ name, body string }{
+		{"readme.txt", "not the mylist"},
+		{"mylist.xml", sampleXML},
+	}
+	for _, f := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: f.name, Mode: 0644, Size: int64(len(f.body))}); err != nil {
+			T.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(f.body)); err != nil {
+			T.Fatal(err)
+		}
+	}
+	tw.Close()
+	gz.Close()
+
+	return buf.Bytes()
+}
+
+func TestParseXML(T *testing.T) {
+	ml, err := mylistexport.Parse(strings.NewReader(sampleXML))
+	if err != nil {
+		T.Fatal(err)
+	}
+	if !reflect.DeepEqual(ml, sampleList) {
+		T.Errorf("Expected %#v, got %#v", sampleList, ml)
+	}
+}
+
+func TestParseArchive(T *testing.T) {
+	ml, err := mylistexport.Parse(bytes.NewReader(makeArchive(T)))
+	if err != nil {
+		T.Fatal(err)
+	}
+	if !reflect.DeepEqual(ml, sampleList) {
+		T.Errorf("Expected %#v, got %#v", sampleList, ml)
+	}
+}
+
+func TestParseEmptyArchive(T *testing.T) {
+	buf := bytes.Buffer{}
+	gz := gzip.NewWriter(&buf)
+	tar.NewWriter(gz).Close()
+	gz.Close()
+
+	if _, err := mylistexport.Parse(&buf); err != mylistexport.NoXMLError {
+		T.Errorf("Expected NoXMLError, got %v", err)
+	}
+}
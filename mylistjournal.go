@@ -0,0 +1,185 @@
+package anidb
+
+import (
+	"github.com/Kovensky/go-fscache"
+	"sync"
+	"time"
+)
+
+// Maximum number of entries kept in each user's mylist journal;
+// the oldest entries are dropped first.
+var MyListJournalLength = 1000
+
+// A mylist modification that was accepted by the server.
+type MyListJournalEntry struct {
+	Type MyListOpType
+	FID  FID
+
+	Before *MyListEntry // nil for adds, or if the entry wasn't known
+	After  *MyListEntry // nil for deletes
+
+	Time time.Time
+}
+
+var myListJournalLock sync.Mutex
+
+func journalMyListOp(uid UID, typ MyListOpType, f *File, before, after *MyListEntry) {
+	myListJournalLock.Lock()
+	defer myListJournalLock.Unlock()
+
+	var journal []MyListJournalEntry
+	Cache.Get(&journal, "mylist-journal", uid)
+
+	journal = append(journal, MyListJournalEntry{
+		Type: typ,
+		FID:  f.FID,
+
+		Before: before,
+		After:  after,
+
+		Time: time.Now(),
+	})
+	if len(journal) > MyListJournalLength {
+		journal = journal[len(journal)-MyListJournalLength:]
+	}
+
+	CacheSet(&journal, "mylist-journal", uid)
+}
+
+// Returns the entry the user has for the file, for journaling purposes.
+// Uses the cached entry if there is one, and MyListByFID otherwise;
+// returns nil if the previous state of the entry is unknown.
+func (adb *AniDB) myListEntryBefore(user *User, f *File) *MyListEntry {
+	if lid := f.LID[user.UID]; lid > 0 {
+		if e := lid.MyListEntry(); e != nil {
+			return e
+		}
+	}
+	return <-adb.MyListByFID(f.FID)
+}
+
+// Removes the given entry from the journal, if it's still there.
+func removeMyListJournalEntry(uid UID, je MyListJournalEntry) {
+	myListJournalLock.Lock()
+	defer myListJournalLock.Unlock()
+
+	var journal []MyListJournalEntry
+	Cache.Get(&journal, "mylist-journal", uid)
+
+	for i := len(journal) - 1; i >= 0; i-- {
+		if journal[i].FID == je.FID && journal[i].Time.Equal(je.Time) {
+			journal = append(journal[:i], journal[i+1:]...)
+			CacheSet(&journal, "mylist-journal", uid)
+			return
+		}
+	}
+}
+
+// Returns a MyListSet that restores the entry's state.
+func (e *MyListEntry) toMyListSet() *MyListSet {
+	state := e.MyListState
+	watched := !e.DateWatched.IsZero()
+	viewDate := e.DateWatched
+	source, storage, other := e.Source, e.Storage, e.Other
+
+	return &MyListSet{
+		State:    &state,
+		Watched:  &watched,
+		ViewDate: &viewDate,
+		Source:   &source,
+		Storage:  &storage,
+		Other:    &other,
+	}
+}
+
+// Returns the current user's mylist journal, oldest first.
+func (adb *AniDB) MyListJournal() []MyListJournalEntry {
+	user := adb.User()
+	if user == nil {
+		return nil
+	}
+
+	myListJournalLock.Lock()
+	defer myListJournalLock.Unlock()
+
+	var journal []MyListJournalEntry
+	Cache.Get(&journal, "mylist-journal", user.UID)
+	return journal
+}
+
+// Undoes the last n mylist modifications in the current user's journal,
+// newest first, by sending compensating commands. Undone modifications are
+// removed from the journal; the compensating commands aren't journaled.
+//
+// Stops at the first modification that can't be undone. Edits and deletes
+// of entries whose previous state wasn't known can't be undone; this happens
+// when the entry couldn't be retrieved before the modification was sent.
+//
+// Returns the number of modifications undone. Uses the UDP API.
+func (adb *AniDB) MyListUndo(n int) <-chan int {
+	ch := make(chan int, 1)
+
+	go func() {
+		user := <-adb.GetCurrentUser()
+		if user == nil || user.UID < 1 || n < 1 {
+			ch <- 0
+			close(ch)
+			return
+		}
+
+		// for the intent map; doesn't get cached
+		key := []fscache.CacheKey{"mylist-undo", user.UID}
+
+		ic := make(chan notification, 1)
+		go func() { ch <- (<-ic).(int); close(ch) }()
+		if intentMap.Intent(ic, key...) {
+			return
+		}
+
+		undone := 0
+		for ; undone < n; undone++ {
+			myListJournalLock.Lock()
+			var journal []MyListJournalEntry
+			Cache.Get(&journal, "mylist-journal", user.UID)
+			myListJournalLock.Unlock()
+
+			if len(journal) == 0 {
+				break
+			}
+			je := journal[len(journal)-1]
+			if !adb.undoMyListOp(user, je) {
+				break
+			}
+			removeMyListJournalEntry(user.UID, je)
+		}
+
+		intentMap.NotifyClose(undone, key...)
+	}()
+	return ch
+}
+
+func (adb *AniDB) undoMyListOp(user *User, je MyListJournalEntry) bool {
+	f := <-adb.FileByID(je.FID)
+	if f == nil {
+		return false
+	}
+
+	switch je.Type {
+	case MyListOpAdd:
+		ok, _ := adb.myListDel(user, f, false)
+		return ok
+	case MyListOpEdit:
+		if je.Before == nil {
+			return false
+		}
+		ok, _ := adb.myListEdit(user, f, je.Before.toMyListSet(), false, false)
+		return ok
+	case MyListOpDel:
+		if je.Before == nil {
+			return false
+		}
+		lid, _ := adb.myListAdd(user, f, je.Before.toMyListSet(), false)
+		return lid > 0
+	}
+	return false
+}
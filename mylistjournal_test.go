@@ -0,0 +1,29 @@
+package anidb
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRemoveMyListJournalEntry(T *testing.T) {
+	defer useTestCache(T)()
+
+	const uid = UID(1)
+	now := time.Now()
+	undone := MyListJournalEntry{Type: MyListOpEdit, FID: 1, Time: now}
+	journal := []MyListJournalEntry{
+		{Type: MyListOpAdd, FID: 1, Time: now.Add(-time.Minute)},
+		undone,
+		// journaled while the undo was in flight
+		{Type: MyListOpAdd, FID: 2, Time: now.Add(time.Second)},
+	}
+	CacheSet(&journal, "mylist-journal", uid)
+
+	removeMyListJournalEntry(uid, undone)
+
+	journal = nil
+	Cache.Get(&journal, "mylist-journal", uid)
+	if len(journal) != 2 || journal[0].FID != 1 || journal[1].FID != 2 {
+		T.Errorf("Expected only the undone entry to be removed, got %v", journal)
+	}
+}
@@ -1,6 +1,8 @@
 package anidb
 
 import (
+	"github.com/Kovensky/go-anidb/misc"
+	"github.com/Kovensky/go-anidb/udp"
 	"github.com/Kovensky/go-fscache"
 	"strconv"
 	"time"
@@ -55,36 +57,7 @@ func (set *MyListSet) update(uid UID, f *File, lid LID) {
 		Cache.Chtime(f.Cached, "fid", f.FID)
 	}
 
-	mla := uid.MyListAnime(f.AID)
-	if mla == nil {
-		mla = &MyListAnime{
-			EpisodesWithState: MyListStateMap{},
-			EpisodesPerGroup:  GroupEpisodes{},
-		}
-	}
-	// We only ever add, not remove -- we don't know if other files also satisfy the list
-	eg := mla.EpisodesPerGroup[f.GID]
-	eg.Add(f.EpisodeNumber)
-	mla.EpisodesPerGroup[f.GID] = eg
-
-	newState := MyListStateUnknown
-	if set != nil {
-		if set.State != nil {
-			newState = *set.State
-		}
-
-		if set.Watched != nil && *set.Watched ||
-			set.ViewDate != nil && !set.ViewDate.IsZero() {
-			mla.WatchedEpisodes.Add(f.EpisodeNumber)
-		}
-	}
-
-	es := mla.EpisodesWithState[newState]
-	es.Add(f.EpisodeNumber)
-	mla.EpisodesWithState[newState] = es
-
-	Cache.Set(mla, "mylist-anime", uid, f.AID)
-	Cache.Chtime(mla.Cached, "mylist-anime", uid, f.AID)
+	set.updateAnime(uid, f.AID, f.GID, f.EpisodeNumber)
 
 	e := lid.MyListEntry()
 	if set == nil ||
@@ -134,6 +107,48 @@ func (set *MyListSet) update(uid UID, f *File, lid LID) {
 	Cache.Chtime(e.Cached, "mylist", lid)
 }
 
+// Adds the given episodes to the cached MyListAnime.
+// If gid is 0, the episodes are not associated with any group.
+func (set *MyListSet) updateAnime(uid UID, aid AID, gid GID, el misc.EpisodeList) {
+	mla := uid.MyListAnime(aid)
+	if mla == nil {
+		mla = &MyListAnime{
+			AID: aid,
+
+			EpisodesWithState: MyListStateMap{},
+			EpisodesPerGroup:  GroupEpisodes{},
+		}
+	}
+	// We only ever add, not remove -- we don't know if other files also satisfy the list
+	if gid > 0 {
+		eg := mla.EpisodesPerGroup[gid]
+		eg.Add(el)
+		mla.EpisodesPerGroup[gid] = eg
+	}
+
+	newState := MyListStateUnknown
+	if set != nil {
+		if set.State != nil {
+			newState = *set.State
+		}
+
+		if set.Watched != nil && *set.Watched ||
+			set.ViewDate != nil && !set.ViewDate.IsZero() {
+			mla.WatchedEpisodes.Add(el)
+		}
+	}
+
+	es := mla.EpisodesWithState[newState]
+	es.Add(el)
+	mla.EpisodesWithState[newState] = es
+
+	Cache.Set(mla, "mylist-anime", uid, aid)
+	Cache.Chtime(mla.Cached, "mylist-anime", uid, aid)
+
+	// the cached entry list may now be missing entries
+	Cache.Delete("mylist-entries", uid, aid)
+}
+
 func (adb *AniDB) MyListAdd(f *File, set *MyListSet) <-chan LID {
 	ch := make(chan LID, 1)
 	if f == nil {
@@ -145,6 +160,10 @@ func (adb *AniDB) MyListAdd(f *File, set *MyListSet) <-chan LID {
 	go func() {
 		user := <-adb.GetCurrentUser()
 		if user == nil || user.UID < 1 {
+			// probably offline; queue it for the last known user
+			if last := adb.lastKnownUser(); adb.MyListQueue && last != nil && last.UID > 0 {
+				queueMyListOp(last.UID, MyListOp{Type: MyListOpAdd, FID: f.FID, Set: set})
+			}
 			ch <- 0
 			close(ch)
 			return
@@ -159,31 +178,46 @@ func (adb *AniDB) MyListAdd(f *File, set *MyListSet) <-chan LID {
 			return
 		}
 
-		pm := set.toParamMap()
-		pm["fid"] = f.FID
+		lid, reply := adb.myListAdd(user, f, set, true)
+		if adb.MyListQueue && isOfflineReply(reply) {
+			queueMyListOp(user.UID, MyListOp{Type: MyListOpAdd, FID: f.FID, Set: set})
+		}
+
+		intentMap.NotifyClose(lid, key...)
+	}()
 
-		reply := <-adb.udp.SendRecv("MYLISTADD", pm)
+	return ch
+}
 
-		lid := LID(0)
+// Sends the MYLISTADD command and updates the cache.
+// If journal is true, successful adds are recorded in the journal.
+func (adb *AniDB) myListAdd(user *User, f *File, set *MyListSet, journal bool) (LID, udpapi.APIReply) {
+	pm := set.toParamMap()
+	pm["fid"] = f.FID
 
-		switch reply.Code() {
-		case 310:
-			e := adb.parseMylistReply(reply)
-			if e != nil {
-				lid = e.LID
-			}
-		case 210:
-			id, _ := strconv.ParseInt(reply.Lines()[1], 10, 64)
-			lid = LID(id)
+	reply := <-adb.udp.SendRecv("MYLISTADD", pm)
+
+	lid := LID(0)
 
-			// the 310 case does this in parseMylistReply
-			set.update(user.UID, f, lid)
+	switch reply.Code() {
+	case 310:
+		e := adb.parseMylistReply(reply)
+		if e != nil {
+			lid = e.LID
 		}
+	case 210:
+		id, _ := strconv.ParseInt(reply.Lines()[1], 10, 64)
+		lid = LID(id)
 
-		intentMap.NotifyClose(lid, key...)
-	}()
+		// the 310 case does this in parseMylistReply
+		set.update(user.UID, f, lid)
+		mirrorMyListEntry(user.UID, f.AID, lid)
 
-	return ch
+		if journal {
+			journalMyListOp(user.UID, MyListOpAdd, f, nil, lid.MyListEntry())
+		}
+	}
+	return lid, reply
 }
 
 func (adb *AniDB) MyListAddByEd2kSize(ed2k string, size int64, set *MyListSet) <-chan LID {
@@ -201,7 +235,106 @@ func (adb *AniDB) MyListAddByEd2kSize(ed2k string, size int64, set *MyListSet) <
 	return ch
 }
 
+// Adds the given episodes of the Anime to the mylist, without needing
+// to know which Files they correspond to. If gid is 0, the episodes are added
+// as generic files, which is useful for DVDs, TV broadcasts, etc.
+// If the EpisodeList is empty, all episodes are added.
+//
+// Returns the number of entries added. Uses the UDP API.
+func (adb *AniDB) MyListAddEpisodes(aid AID, gid GID, el misc.EpisodeList, set *MyListSet) <-chan int {
+	ch := make(chan int, 1)
+	if aid < 1 || gid < 0 {
+		ch <- 0
+		close(ch)
+		return ch
+	}
+
+	go func() {
+		user := <-adb.GetCurrentUser()
+		if user == nil || user.UID < 1 {
+			ch <- 0
+			close(ch)
+			return
+		}
+
+		// for the intent map; doesn't get cached
+		key := []fscache.CacheKey{"mylist-add", user.UID, "by-aid", aid, gid, el.String()}
+
+		ic := make(chan notification, 1)
+		go func() { ch <- (<-ic).(int); close(ch) }()
+		if intentMap.Intent(ic, key...) {
+			return
+		}
+
+		var a *Anime
+		epnos := []string{}
+		switch {
+		case len(el) == 0:
+			epnos = append(epnos, "0") // all episodes
+			el = misc.EpisodeList{}
+			if a = <-adb.AnimeByID(aid); a != nil {
+				for _, ep := range a.Episodes {
+					el.Add(&ep.Episode)
+				}
+			}
+		default:
+			for _, er := range el {
+				if er.Type == misc.EpisodeTypeRegular && er.End != nil &&
+					er.Start.Number == 1 && er.Start.Part < 0 && er.End.Part < 0 {
+					// negative numbers mean "all episodes up to"
+					epnos = append(epnos, "-"+strconv.Itoa(er.End.Number))
+					continue
+				}
+
+				if a == nil {
+					a = <-adb.AnimeByID(aid)
+				}
+				for _, ep := range a.EpisodeList(er) {
+					epnos = append(epnos, ep.Episode.String())
+				}
+			}
+		}
+
+		added := 0
+		for _, epno := range epnos {
+			pm := set.toParamMap()
+			pm["aid"] = aid
+			pm["epno"] = epno
+			if gid > 0 {
+				pm["gid"] = gid
+			} else {
+				pm["generic"] = 1
+			}
+
+			reply := <-adb.udp.SendRecv("MYLISTADD", pm)
+
+			switch reply.Code() {
+			case 210:
+				n, _ := strconv.ParseInt(reply.Lines()[1], 10, 32)
+				added += int(n)
+			case 310:
+				// already in mylist, nothing was added
+				adb.parseMylistReply(reply)
+			}
+		}
+
+		if added > 0 {
+			set.updateAnime(user.UID, aid, gid, el)
+		}
+
+		intentMap.NotifyClose(added, key...)
+	}()
+
+	return ch
+}
+
 func (adb *AniDB) MyListEdit(f *File, set *MyListSet) <-chan bool {
+	return adb.myListEditAsync(f, set, false)
+}
+
+// Like MyListEdit; if low is true, the command is sent with
+// SendRecvLowPriority.
+func (adb *AniDB) myListEditAsync(f *File, set *MyListSet, low bool) <-chan bool {
 	ch := make(chan bool, 1)
 	if f == nil {
 		ch <- false
@@ -212,6 +345,10 @@ func (adb *AniDB) MyListEdit(f *File, set *MyListSet) <-chan bool {
 	go func() {
 		user := <-adb.GetCurrentUser()
 		if user == nil || user.UID < 1 {
+			// probably offline; queue it for the last known user
+			if last := adb.lastKnownUser(); adb.MyListQueue && last != nil && last.UID > 0 {
+				queueMyListOp(last.UID, MyListOp{Type: MyListOpEdit, FID: f.FID, Set: set})
+			}
 			ch <- false
 			close(ch)
 			return
@@ -226,31 +363,55 @@ func (adb *AniDB) MyListEdit(f *File, set *MyListSet) <-chan bool {
 			return
 		}
 
-		pm := set.toParamMap()
-		pm["edit"] = 1
-		if lid := f.LID[user.UID]; lid > 0 {
-			pm["lid"] = lid
-		} else {
-			pm["fid"] = f.FID
+		ok, reply := adb.myListEdit(user, f, set, true, low)
+		if adb.MyListQueue && isOfflineReply(reply) {
+			queueMyListOp(user.UID, MyListOp{Type: MyListOpEdit, FID: f.FID, Set: set})
 		}
 
-		reply := <-adb.udp.SendRecv("MYLISTADD", pm)
-
-		switch reply.Code() {
-		case 311:
-			intentMap.NotifyClose(true, key...)
-
-			set.update(user.UID, f, 0)
-		default:
-			intentMap.NotifyClose(false, key...)
-		}
+		intentMap.NotifyClose(ok, key...)
 	}()
 
 	return ch
 }
 
+// Sends the MYLISTADD edit command and updates the cache.
+// If journal is true, successful edits are recorded in the journal.
+// If low is true, the command is sent with SendRecvLowPriority.
+func (adb *AniDB) myListEdit(user *User, f *File, set *MyListSet, journal, low bool) (bool, udpapi.APIReply) {
+	var before *MyListEntry
+	if journal {
+		before = adb.myListEntryBefore(user, f)
+	}
+
+	pm := set.toParamMap()
+	pm["edit"] = 1
+	if lid := f.LID[user.UID]; lid > 0 {
+		pm["lid"] = lid
+	} else {
+		pm["fid"] = f.FID
+	}
+
+	send := adb.udp.SendRecv
+	if low {
+		send = adb.udp.SendRecvLowPriority
+	}
+	reply := <-send("MYLISTADD", pm)
+
+	switch reply.Code() {
+	case 311:
+		lid := f.LID[user.UID]
+		set.update(user.UID, f, 0)
+
+		if journal {
+			journalMyListOp(user.UID, MyListOpEdit, f, before, lid.MyListEntry())
+		}
+		return true, reply
+	}
+	return false, reply
+}
+
 func (adb *AniDB) MyListDel(f *File) <-chan bool {
-	ch := make(chan bool)
+	ch := make(chan bool, 1)
 	if f == nil {
 		ch <- false
 		close(ch)
@@ -260,6 +421,10 @@ func (adb *AniDB) MyListDel(f *File) <-chan bool {
 	go func() {
 		user := <-adb.GetCurrentUser()
 		if user == nil || user.UID < 1 {
+			// probably offline; queue it for the last known user
+			if last := adb.lastKnownUser(); adb.MyListQueue && last != nil && last.UID > 0 {
+				queueMyListOp(last.UID, MyListOp{Type: MyListOpDel, FID: f.FID})
+			}
 			ch <- false
 			close(ch)
 			return
@@ -274,26 +439,46 @@ func (adb *AniDB) MyListDel(f *File) <-chan bool {
 			return
 		}
 
-		pm := paramMap{}
-		if lid := f.LID[user.UID]; lid > 0 {
-			pm["lid"] = lid
-		} else {
-			pm["fid"] = f.FID
+		ok, reply := adb.myListDel(user, f, true)
+		if adb.MyListQueue && isOfflineReply(reply) {
+			queueMyListOp(user.UID, MyListOp{Type: MyListOpDel, FID: f.FID})
 		}
 
-		reply := <-adb.udp.SendRecv("MYLISTDEL", pm)
-
-		switch reply.Code() {
-		case 211:
-			delete(f.LID, user.UID)
-			Cache.Set(f, "fid", f.FID)
-			Cache.Chtime(f.Cached, "fid", f.FID)
-
-			intentMap.NotifyClose(true, key...)
-		default:
-			intentMap.NotifyClose(false, key...)
-		}
+		intentMap.NotifyClose(ok, key...)
 	}()
 
 	return ch
 }
+
+// Sends the MYLISTDEL command and updates the cache.
+// If journal is true, successful deletes are recorded in the journal.
+func (adb *AniDB) myListDel(user *User, f *File, journal bool) (bool, udpapi.APIReply) {
+	var before *MyListEntry
+	if journal {
+		before = adb.myListEntryBefore(user, f)
+	}
+
+	pm := paramMap{}
+	if lid := f.LID[user.UID]; lid > 0 {
+		pm["lid"] = lid
+	} else {
+		pm["fid"] = f.FID
+	}
+
+	reply := <-adb.udp.SendRecv("MYLISTDEL", pm)
+
+	switch reply.Code() {
+	case 211:
+		unmirrorMyListEntry(user.UID, f.AID, f.LID[user.UID])
+
+		delete(f.LID, user.UID)
+		Cache.Set(f, "fid", f.FID)
+		Cache.Chtime(f.Cached, "fid", f.FID)
+
+		if journal {
+			journalMyListOp(user.UID, MyListOpDel, f, before, nil)
+		}
+		return true, reply
+	}
+	return false, reply
+}
@@ -0,0 +1,391 @@
+package anidb
+
+import (
+	"github.com/Kovensky/go-anidb/udp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The index of a user's mylist, stored in the cache. The LIDs of each anime
+// are stored separately, so that updates only rewrite the anime that changed.
+// The entries themselves are stored in the regular mylist cache.
+type myListIndex struct {
+	AIDs []AID
+
+	Synced time.Time // When the last full Sync happened
+}
+
+var myListIndexLock sync.Mutex
+
+func loadMyListIndex(uid UID) *myListIndex {
+	idx := &myListIndex{}
+	Cache.Get(idx, "mylist-mirror", uid, "index")
+	return idx
+}
+
+func (idx *myListIndex) store(uid UID) {
+	Cache.Set(idx, "mylist-mirror", uid, "index")
+}
+
+func loadMirrorLIDs(uid UID, aid AID) []LID {
+	var lids []LID
+	Cache.Get(&lids, "mylist-mirror", uid, aid)
+	return lids
+}
+
+// Stores the anime's LIDs, updating the index if the anime was
+// added to or removed from the mirror.
+func storeMirrorLIDs(uid UID, aid AID, lids []LID) {
+	idx := loadMyListIndex(uid)
+	pos := -1
+	for i, a := range idx.AIDs {
+		if a == aid {
+			pos = i
+			break
+		}
+	}
+
+	if len(lids) == 0 {
+		Cache.Delete("mylist-mirror", uid, aid)
+		if pos >= 0 {
+			idx.AIDs = append(idx.AIDs[:pos], idx.AIDs[pos+1:]...)
+			idx.store(uid)
+		}
+		return
+	}
+
+	Cache.Set(&lids, "mylist-mirror", uid, aid)
+	if pos < 0 {
+		idx.AIDs = append(idx.AIDs, aid)
+		idx.store(uid)
+	}
+}
+
+func containsLID(lids []LID, lid LID) bool {
+	for _, l := range lids {
+		if l == lid {
+			return true
+		}
+	}
+	return false
+}
+
+func sameLIDs(a, b []LID) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, lid := range a {
+		if !containsLID(b, lid) {
+			return false
+		}
+	}
+	return true
+}
+
+// Called whenever an entry is cached, to keep the mirror up-to-date.
+func mirrorMyListEntry(uid UID, aid AID, lid LID) {
+	if uid < 1 || aid < 1 || lid < 1 {
+		return
+	}
+
+	myListIndexLock.Lock()
+	defer myListIndexLock.Unlock()
+
+	if lids := loadMirrorLIDs(uid, aid); !containsLID(lids, lid) {
+		storeMirrorLIDs(uid, aid, append(lids, lid))
+	}
+}
+
+// Called whenever an entry is deleted from the mylist.
+func unmirrorMyListEntry(uid UID, aid AID, lid LID) {
+	if uid < 1 || aid < 1 || lid < 1 {
+		return
+	}
+
+	myListIndexLock.Lock()
+	defer myListIndexLock.Unlock()
+
+	lids := loadMirrorLIDs(uid, aid)
+	for i, l := range lids {
+		if l == lid {
+			storeMirrorLIDs(uid, aid, append(lids[:i], lids[i+1:]...))
+			return
+		}
+	}
+}
+
+// Replaces the anime's LIDs with the ones found by the API.
+//
+// Generic entries aren't found by MyListEntriesForAnime, so entries
+// without a group are kept as long as they're still cached.
+func resyncMirrorLIDs(uid UID, aid AID, found []LID) {
+	myListIndexLock.Lock()
+	defer myListIndexLock.Unlock()
+
+	old := loadMirrorLIDs(uid, aid)
+	lids := append([]LID{}, found...)
+	for _, lid := range old {
+		if containsLID(lids, lid) {
+			continue
+		}
+		if e := lid.MyListEntry(); e != nil && e.GID == 0 &&
+			Cache.IsValid(InvalidKeyCacheDuration, "mylist", lid) {
+			lids = append(lids, lid)
+		}
+	}
+
+	if !sameLIDs(old, lids) {
+		storeMirrorLIDs(uid, aid, lids)
+	}
+}
+
+// A complete local copy of a user's mylist, which can be queried
+// without accessing the API.
+//
+// The mirror is kept up-to-date with every mylist reply, add, edit and
+// delete done through the library. It should be seeded with Import (for
+// example, from a mylist export), and refreshed with Sync and Listen.
+type MyListMirror struct {
+	adb *AniDB
+	UID UID
+}
+
+// Returns the mylist mirror of the current user, or nil if not logged in.
+func (adb *AniDB) MyListMirror() *MyListMirror {
+	user := adb.User()
+	if user == nil || user.UID < 1 {
+		return nil
+	}
+	return &MyListMirror{adb: adb, UID: user.UID}
+}
+
+// Returns when the mirror was last fully synced.
+func (m *MyListMirror) Synced() time.Time {
+	myListIndexLock.Lock()
+	defer myListIndexLock.Unlock()
+
+	return loadMyListIndex(m.UID).Synced
+}
+
+// Caches the given entries and adds them to the mirror.
+// Entries must belong to the mirror's user.
+func (m *MyListMirror) Import(entries ...*MyListEntry) {
+	myListIndexLock.Lock()
+	defer myListIndexLock.Unlock()
+
+	added := map[AID][]LID{}
+	for _, e := range entries {
+		if e == nil || e.LID < 1 || e.AID < 1 {
+			continue
+		}
+		if e.Cached.IsZero() {
+			CacheSet(e, "mylist", e.LID)
+		} else {
+			Cache.Set(e, "mylist", e.LID)
+			Cache.Chtime(e.Cached, "mylist", e.LID)
+		}
+		added[e.AID] = append(added[e.AID], e.LID)
+	}
+
+	for aid, lids := range added {
+		old := loadMirrorLIDs(m.UID, aid)
+		merged := old
+		for _, lid := range lids {
+			if !containsLID(merged, lid) {
+				merged = append(merged, lid)
+			}
+		}
+		if len(merged) != len(old) {
+			storeMirrorLIDs(m.UID, aid, merged)
+		}
+	}
+}
+
+// Refreshes every anime in the mirror with MyListEntriesForAnime, which only
+// queries the API for anime whose cached entry list is stale. Entries that
+// are no longer listed for their anime are removed.
+//
+// Returns false if there was an API error; the mirror is still updated
+// with everything that could be retrieved.
+func (m *MyListMirror) Sync() <-chan bool {
+	ch := make(chan bool, 1)
+
+	go func() {
+		ok := true
+		for _, aid := range m.AIDs() {
+			ok = m.syncAnime(aid) && ok
+		}
+
+		if ok {
+			myListIndexLock.Lock()
+			idx := loadMyListIndex(m.UID)
+			idx.Synced = time.Now()
+			idx.store(m.UID)
+			myListIndexLock.Unlock()
+		}
+
+		ch <- ok
+		close(ch)
+	}()
+	return ch
+}
+
+func (m *MyListMirror) syncAnime(aid AID) bool {
+	ok := true
+	found := []LID{}
+	for e := range m.adb.MyListEntriesForAnime(aid) {
+		if e == nil {
+			ok = false
+		} else {
+			found = append(found, e.LID)
+		}
+	}
+
+	if ok {
+		resyncMirrorLIDs(m.UID, aid, found)
+	} else {
+		// the list is incomplete; only add what was found
+		for _, lid := range found {
+			mirrorMyListEntry(m.UID, aid, lid)
+		}
+	}
+	return ok
+}
+
+// Enables PUSH notifications and, until the stop channel is closed, resyncs
+// anime in the mirror when new file notifications arrive for them, as the
+// notify list may add new files to the mylist automatically. Notifications
+// about anime that aren't in the mirror, groups and messages are ignored.
+//
+// Other subscribers still receive every notification.
+func (m *MyListMirror) Listen(stop <-chan bool) {
+	notifications, unsubscribe := m.adb.udp.subscribeNotifications()
+	<-m.adb.udp.SendRecv("PUSH", paramMap{"notify": 1, "msg": 0})
+
+	go func() {
+		for {
+			select {
+			case <-stop:
+				if unsubscribe() == 0 {
+					<-m.adb.udp.SendRecv("PUSH", paramMap{"notify": 0, "msg": 0})
+				}
+				return
+			case n := <-notifications:
+				if aid := newFileNotificationAID(n); aid > 0 && m.hasAnime(aid) {
+					Cache.Delete("mylist-entries", m.UID, aid)
+					m.syncAnime(aid)
+				}
+			}
+		}
+	}()
+}
+
+// Returns the AID of a new file notification about an anime,
+// or 0 for any other notification.
+func newFileNotificationAID(n udpapi.APIReply) AID {
+	if n.Code() != 720 || len(n.Lines()) < 2 {
+		return 0
+	}
+	// {int4 relid}|{int4 type}|{int4 priority}; type 1 is a group
+	parts := strings.Split(n.Lines()[1], "|")
+	if len(parts) < 2 || parts[1] == "1" {
+		return 0
+	}
+	id, err := strconv.ParseInt(parts[0], 10, 32)
+	if err != nil {
+		return 0
+	}
+	return AID(id)
+}
+
+func (m *MyListMirror) hasAnime(aid AID) bool {
+	myListIndexLock.Lock()
+	defer myListIndexLock.Unlock()
+
+	for _, a := range loadMyListIndex(m.UID).AIDs {
+		if a == aid {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the AIDs of all anime in the mirror, sorted.
+func (m *MyListMirror) AIDs() []AID {
+	myListIndexLock.Lock()
+	defer myListIndexLock.Unlock()
+
+	idx := loadMyListIndex(m.UID)
+	aids := make([]int, 0, len(idx.AIDs))
+	for _, aid := range idx.AIDs {
+		aids = append(aids, int(aid))
+	}
+	sort.Ints(aids)
+
+	ret := make([]AID, len(aids))
+	for i := range aids {
+		ret[i] = AID(aids[i])
+	}
+	return ret
+}
+
+// Returns the cached entries for the given anime.
+func (m *MyListMirror) EntriesForAnime(aid AID) []*MyListEntry {
+	myListIndexLock.Lock()
+	lids := loadMirrorLIDs(m.UID, aid)
+	myListIndexLock.Unlock()
+
+	entries := make([]*MyListEntry, 0, len(lids))
+	for _, lid := range lids {
+		if e := lid.MyListEntry(); e != nil {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// Returns all cached entries that satisfy the filter.
+// If filter is nil, returns all entries.
+func (m *MyListMirror) Entries(filter func(*MyListEntry) bool) []*MyListEntry {
+	entries := []*MyListEntry{}
+	for _, aid := range m.AIDs() {
+		for _, e := range m.EntriesForAnime(aid) {
+			if filter == nil || filter(e) {
+				entries = append(entries, e)
+			}
+		}
+	}
+	return entries
+}
+
+// Returns all entries stored in the given storage.
+func (m *MyListMirror) EntriesOnStorage(storage string) []*MyListEntry {
+	return m.Entries(func(e *MyListEntry) bool { return e.Storage == storage })
+}
+
+// Returns all entries with the given MyListState.
+func (m *MyListMirror) EntriesWithState(state MyListState) []*MyListEntry {
+	return m.Entries(func(e *MyListEntry) bool { return e.MyListState == state })
+}
+
+// Returns the AIDs of the anime where every entry has been watched.
+func (m *MyListMirror) WatchedAnime() []AID {
+	aids := []AID{}
+	for _, aid := range m.AIDs() {
+		entries := m.EntriesForAnime(aid)
+		watched := len(entries) > 0
+		for _, e := range entries {
+			if e.DateWatched.IsZero() {
+				watched = false
+				break
+			}
+		}
+		if watched {
+			aids = append(aids, aid)
+		}
+	}
+	return aids
+}
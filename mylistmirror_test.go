@@ -0,0 +1,34 @@
+package anidb
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMyListMirrorResync(T *testing.T) {
+	defer useTestCache(T)()
+
+	const uid = UID(1)
+	m := &MyListMirror{UID: uid}
+
+	generic := &MyListEntry{LID: 9000003, AID: 1}
+	m.Import(
+		&MyListEntry{LID: 9000001, AID: 1, GID: 1},
+		&MyListEntry{LID: 9000002, AID: 1, GID: 1},
+		generic,
+		&MyListEntry{LID: 9000004, AID: 2, GID: 1},
+	)
+	if aids := m.AIDs(); !reflect.DeepEqual(aids, []AID{1, 2}) {
+		T.Fatalf("expected AIDs [1 2], got %v", aids)
+	}
+
+	resyncMirrorLIDs(uid, 1, []LID{9000001})
+	if lids := loadMirrorLIDs(uid, 1); !reflect.DeepEqual(lids, []LID{9000001, 9000003}) {
+		T.Errorf("expected the unlisted entry to be removed and the generic entry kept, got %v", lids)
+	}
+
+	resyncMirrorLIDs(uid, 2, nil)
+	if aids := m.AIDs(); !reflect.DeepEqual(aids, []AID{1}) {
+		T.Errorf("expected anime without entries to be removed, got %v", aids)
+	}
+}
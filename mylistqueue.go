@@ -0,0 +1,282 @@
+package anidb
+
+import (
+	"github.com/Kovensky/go-anidb/udp"
+	"github.com/Kovensky/go-fscache"
+	"sync"
+	"time"
+)
+
+// See the constants list for valid values.
+type MyListOpType int
+
+const (
+	MyListOpAdd = MyListOpType(1 + iota)
+	MyListOpEdit
+	MyListOpDel
+)
+
+func (t MyListOpType) String() string {
+	switch t {
+	case MyListOpAdd:
+		return "Add"
+	case MyListOpEdit:
+		return "Edit"
+	case MyListOpDel:
+		return "Delete"
+	default:
+		return "Unknown"
+	}
+}
+
+// A mylist modification waiting to be sent to the server.
+type MyListOp struct {
+	Type MyListOpType
+	FID  FID
+	Set  *MyListSet // nil for MyListOpDel
+
+	Queued time.Time
+
+	// Whether the op is being sent to the server. Later ops for the
+	// same file are queued after it instead of being merged into it.
+	// Only kept in memory, see myListReplaying.
+	replaying bool
+}
+
+// Returns whether the reply means the command couldn't reach the server,
+// as opposed to the server rejecting it.
+func isOfflineReply(reply udpapi.APIReply) bool {
+	switch reply.Code() {
+	// 501, 506: not logged in
+	// 555: banned
+	// 601, 602, 604: server down, busy or timing out
+	// 999: local timeout or network error
+	case 501, 506, 555, 601, 602, 604, 999:
+		return true
+	}
+	return false
+}
+
+// Merges the non-nil fields of b into a copy of a.
+func (a *MyListSet) merge(b *MyListSet) *MyListSet {
+	c := MyListSet{}
+	if a != nil {
+		c = *a
+	}
+	if b == nil {
+		return &c
+	}
+
+	if b.State != nil {
+		c.State = b.State
+	}
+	if b.Watched != nil {
+		c.Watched = b.Watched
+	}
+	if b.ViewDate != nil {
+		c.ViewDate = b.ViewDate
+	}
+	if b.Source != nil {
+		c.Source = b.Source
+	}
+	if b.Storage != nil {
+		c.Storage = b.Storage
+	}
+	if b.Other != nil {
+		c.Other = b.Other
+	}
+	return &c
+}
+
+// Appends op to the queue, collapsing it with a queued op
+// for the same file when possible.
+func collapseMyListOp(queue []MyListOp, op MyListOp) []MyListOp {
+	last := -1
+	for i := range queue {
+		if queue[i].FID == op.FID {
+			last = i
+		}
+	}
+	if last < 0 || queue[last].replaying {
+		return append(queue, op)
+	}
+
+	prev := &queue[last]
+	switch {
+	case prev.Type == MyListOpAdd && op.Type == MyListOpDel:
+		// never sent, so there's nothing to delete
+		return append(queue[:last], queue[last+1:]...)
+	case prev.Type == MyListOpAdd && op.Type != MyListOpDel,
+		prev.Type == MyListOpEdit && op.Type == MyListOpEdit:
+		// add then edit is still an add; edits stack
+		prev.Set = prev.Set.merge(op.Set)
+		return queue
+	case prev.Type == MyListOpEdit && op.Type == MyListOpDel:
+		*prev = op
+		return queue
+	}
+	return append(queue, op)
+}
+
+// Returns the last user known to be logged in, without using the API.
+func (adb *AniDB) lastKnownUser() *User {
+	switch {
+	case adb.udp.user != nil:
+		return adb.udp.user
+	case adb.udp.credentials != nil:
+		return UserByName(decrypt(adb.udp.credentials.username))
+	}
+	return nil
+}
+
+var myListQueueLock sync.Mutex
+
+// The op each user's ReplayMyListQueue is sending, if any.
+// Protected by myListQueueLock.
+var myListReplaying = map[UID]MyListOp{}
+
+func queueMyListOp(uid UID, op MyListOp) {
+	myListQueueLock.Lock()
+	defer myListQueueLock.Unlock()
+
+	if op.Queued.IsZero() {
+		op.Queued = time.Now()
+	}
+
+	var queue []MyListOp
+	Cache.Get(&queue, "mylist-queue", uid)
+
+	if r, ok := myListReplaying[uid]; ok && len(queue) > 0 &&
+		queue[0].FID == r.FID && queue[0].Queued.Equal(r.Queued) {
+		queue[0].replaying = true
+	}
+
+	queue = collapseMyListOp(queue, op)
+	if len(queue) == 0 {
+		Cache.Delete("mylist-queue", uid)
+	} else {
+		CacheSet(&queue, "mylist-queue", uid)
+	}
+}
+
+// Returns the mylist modifications of the current user that are waiting
+// to be sent to the server, in the order they'll be sent.
+func (adb *AniDB) PendingMyListOps() []MyListOp {
+	user := adb.User()
+	if user == nil {
+		return nil
+	}
+
+	myListQueueLock.Lock()
+	defer myListQueueLock.Unlock()
+
+	var queue []MyListOp
+	Cache.Get(&queue, "mylist-queue", user.UID)
+	return queue
+}
+
+// Sends the queued mylist modifications of the current user to the server,
+// in order. Stops at the first modification that can't reach the server.
+//
+// Modifications that are rejected by the server are dropped, except for
+// adds of files that are already in the mylist, which are retried as edits.
+//
+// Called automatically after authenticating. Returns the number of
+// modifications still waiting.
+func (adb *AniDB) ReplayMyListQueue() <-chan int {
+	ch := make(chan int, 1)
+
+	go func() {
+		user := <-adb.GetCurrentUser()
+		if user == nil || user.UID < 1 {
+			ch <- 0
+			close(ch)
+			return
+		}
+
+		// for the intent map; doesn't get cached
+		key := []fscache.CacheKey{"mylist-queue-replay", user.UID}
+
+		ic := make(chan notification, 1)
+		go func() { ch <- (<-ic).(int); close(ch) }()
+		if intentMap.Intent(ic, key...) {
+			return
+		}
+
+		for !Banned() {
+			myListQueueLock.Lock()
+			var queue []MyListOp
+			Cache.Get(&queue, "mylist-queue", user.UID)
+
+			if len(queue) == 0 {
+				myListQueueLock.Unlock()
+				break
+			}
+			op := queue[0]
+			myListReplaying[user.UID] = op
+			myListQueueLock.Unlock()
+
+			sent := adb.replayMyListOp(user, op)
+
+			myListQueueLock.Lock()
+			delete(myListReplaying, user.UID)
+			if sent {
+				queue = nil
+				Cache.Get(&queue, "mylist-queue", user.UID)
+				if len(queue) > 0 && queue[0].FID == op.FID && queue[0].Queued.Equal(op.Queued) {
+					queue = queue[1:]
+				}
+				if len(queue) == 0 {
+					Cache.Delete("mylist-queue", user.UID)
+				} else {
+					CacheSet(&queue, "mylist-queue", user.UID)
+				}
+			}
+			myListQueueLock.Unlock()
+
+			if !sent {
+				break
+			}
+		}
+
+		intentMap.NotifyClose(len(adb.PendingMyListOps()), key...)
+	}()
+	return ch
+}
+
+// Returns false if the op couldn't reach the server and must be kept.
+func (adb *AniDB) replayMyListOp(user *User, op MyListOp) bool {
+	f := <-adb.FileByID(op.FID)
+	if f == nil {
+		if Banned() || Cache.IsValid(InvalidKeyCacheDuration, "fid", op.FID) {
+			// couldn't get the file, try again later
+			return false
+		}
+		adb.Logger.Printf("UDP!!! Dropping queued mylist %s of deleted FID %d", op.Type, op.FID)
+		return true
+	}
+
+	var reply udpapi.APIReply
+	switch op.Type {
+	case MyListOpAdd:
+		_, reply = adb.myListAdd(user, f, op.Set, true)
+		if reply.Code() == 310 {
+			// already in mylist; apply the changes we wanted
+			_, reply = adb.myListEdit(user, f, op.Set, true, false)
+		}
+	case MyListOpEdit:
+		_, reply = adb.myListEdit(user, f, op.Set, true, false)
+	case MyListOpDel:
+		_, reply = adb.myListDel(user, f, true)
+	default:
+		return true
+	}
+
+	if isOfflineReply(reply) {
+		return false
+	}
+	if reply.Error() != nil {
+		adb.Logger.Printf("UDP!!! Dropping queued mylist %s of FID %d: %v", op.Type, op.FID, reply.Error())
+	}
+	return true
+}
@@ -0,0 +1,25 @@
+package anidb
+
+import (
+	"testing"
+)
+
+func TestCollapseMyListOpReplaying(T *testing.T) {
+	T.Parallel()
+
+	watched := true
+	queue := []MyListOp{{Type: MyListOpEdit, FID: 1, Set: &MyListSet{}, replaying: true}}
+
+	queue = collapseMyListOp(queue, MyListOp{Type: MyListOpEdit, FID: 1, Set: &MyListSet{Watched: &watched}})
+	if len(queue) != 2 {
+		T.Fatalf("expected the edit to be queued after the replaying op, got %v", queue)
+	}
+	if queue[0].Set.Watched != nil {
+		T.Errorf("the replaying op was modified: %v", queue[0].Set)
+	}
+
+	queue = collapseMyListOp(queue, MyListOp{Type: MyListOpDel, FID: 1})
+	if len(queue) != 2 || queue[1].Type != MyListOpDel {
+		T.Errorf("expected the queued edit to become a delete, got %v", queue)
+	}
+}
@@ -0,0 +1,349 @@
+package probe
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"strings"
+	"time"
+)
+
+// Matroska element IDs, including their length markers.
+const (
+	ebmlHeader  = 0x1A45DFA3
+	ebmlDocType = 0x4282
+
+	mkvSegment       = 0x18538067
+	mkvInfo          = 0x1549A966
+	mkvTimecodeScale = 0x2AD7B1
+	mkvDuration      = 0x4489
+	mkvTracks        = 0x1654AE6B
+	mkvCluster       = 0x1F43B675
+
+	mkvTrackEntry     = 0xAE
+	mkvTrackType      = 0x83
+	mkvCodecID        = 0x86
+	mkvCodecPrivate   = 0x63A2
+	mkvLanguage       = 0x22B59C
+	mkvVideo          = 0xE0
+	mkvPixelWidth     = 0xB0
+	mkvPixelHeight    = 0xBA
+	mkvColour         = 0x55B0
+	mkvBitsPerChannel = 0x55B2
+	mkvAudio          = 0xE1
+	mkvChannels       = 0x9F
+)
+
+const (
+	mkvTrackVideo    = 1
+	mkvTrackAudio    = 2
+	mkvTrackSubtitle = 17
+)
+
+const unknownSize = -1
+
+var malformedError = errors.New("probe: malformed file")
+
+// Reads an EBML variable length integer. If marker is false, the length
+// marker is removed from the value, as for element sizes.
+func readVint(r io.Reader, marker bool) (v int64, n int, err error) {
+	b := make([]byte, 1)
+	if _, err = io.ReadFull(r, b); err != nil {
+		return
+	}
+
+	n = 1
+	for mask := byte(0x80); n <= 8 && b[0]&mask == 0; mask >>= 1 {
+		n++
+	}
+	if n > 8 {
+		return 0, 0, malformedError
+	}
+
+	v = int64(b[0])
+	if !marker {
+		v &= int64(0xFF >> uint(n))
+	}
+	allOnes := v == int64(0xFF>>uint(n))
+
+	rest := make([]byte, n-1)
+	if _, err = io.ReadFull(r, rest); err != nil {
+		return
+	}
+	for _, c := range rest {
+		v = v<<8 | int64(c)
+		allOnes = allOnes && c == 0xFF
+	}
+
+	if !marker && allOnes {
+		v = unknownSize
+	}
+	return
+}
+
+type ebmlElement struct {
+	id   int64
+	size int64 // unknownSize if not known
+	data int64 // offset of the element's data
+}
+
+func readElement(r io.ReadSeeker) (el ebmlElement, err error) {
+	if el.id, _, err = readVint(r, true); err != nil {
+		return
+	}
+	if el.size, _, err = readVint(r, false); err != nil {
+		return
+	}
+	el.data, err = r.Seek(0, 1)
+	return
+}
+
+// Calls fn for every child of the element spanning [start, end).
+// If end is negative, reads until EOF. fn must leave the reader anywhere
+// inside the child, or at its end; returning false stops the iteration.
+func ebmlChildren(r io.ReadSeeker, start, end int64, fn func(el ebmlElement) (bool, error)) error {
+	pos := start
+	for end < 0 || pos < end {
+		if _, err := r.Seek(pos, 0); err != nil {
+			return err
+		}
+		el, err := readElement(r)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		cont, err := fn(el)
+		if err != nil || !cont {
+			return err
+		}
+		if el.size == unknownSize {
+			// only the segment and clusters are normally of unknown size
+			return nil
+		}
+		pos = el.data + el.size
+	}
+	return nil
+}
+
+func readBytes(r io.Reader, el ebmlElement) ([]byte, error) {
+	if el.size < 0 || el.size > 1<<20 {
+		return nil, malformedError
+	}
+	b := make([]byte, el.size)
+	_, err := io.ReadFull(r, b)
+	return b, err
+}
+
+func readUint(r io.Reader, el ebmlElement) (uint64, error) {
+	b, err := readBytes(r, el)
+	if err != nil || len(b) > 8 {
+		return 0, malformedError
+	}
+	v := uint64(0)
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v, nil
+}
+
+func readFloat(r io.Reader, el ebmlElement) (float64, error) {
+	b, err := readBytes(r, el)
+	if err != nil {
+		return 0, err
+	}
+	switch len(b) {
+	case 4:
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), nil
+	case 8:
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), nil
+	}
+	return 0, malformedError
+}
+
+func readString(r io.Reader, el ebmlElement) (string, error) {
+	b, err := readBytes(r, el)
+	return strings.TrimRight(string(b), "\x00"), err
+}
+
+func end(el ebmlElement) int64 {
+	if el.size == unknownSize {
+		return -1
+	}
+	return el.data + el.size
+}
+
+func probeMatroska(r io.ReadSeeker) (*Info, error) {
+	info := &Info{Format: "matroska"}
+
+	header, err := readElement(r)
+	if err != nil || header.id != ebmlHeader {
+		return nil, UnknownFormatError
+	}
+	err = ebmlChildren(r, header.data, end(header), func(el ebmlElement) (bool, error) {
+		if el.id == ebmlDocType {
+			doc, err := readString(r, el)
+			if doc == "webm" {
+				info.Format = doc
+			}
+			return true, err
+		}
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := r.Seek(end(header), 0); err != nil {
+		return nil, err
+	}
+	segment, err := readElement(r)
+	if err != nil || segment.id != mkvSegment {
+		return nil, malformedError
+	}
+
+	scale := uint64(1000000)
+	duration := 0.0
+	seenInfo, seenTracks := false, false
+
+	err = ebmlChildren(r, segment.data, end(segment), func(el ebmlElement) (bool, error) {
+		switch el.id {
+		case mkvInfo:
+			seenInfo = true
+			return !seenTracks, ebmlChildren(r, el.data, end(el), func(el ebmlElement) (bool, error) {
+				var err error
+				switch el.id {
+				case mkvTimecodeScale:
+					scale, err = readUint(r, el)
+				case mkvDuration:
+					duration, err = readFloat(r, el)
+				}
+				return true, err
+			})
+		case mkvTracks:
+			seenTracks = true
+			return !seenInfo, ebmlChildren(r, el.data, end(el), func(el ebmlElement) (bool, error) {
+				if el.id == mkvTrackEntry {
+					return true, info.readMatroskaTrack(r, el)
+				}
+				return true, nil
+			})
+		case mkvCluster:
+			// the headers normally come before the first cluster
+			return false, nil
+		}
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	info.Duration = time.Duration(duration * float64(scale))
+	return info, nil
+}
+
+func (info *Info) readMatroskaTrack(r io.ReadSeeker, entry ebmlElement) error {
+	typ := uint64(0)
+	codec, lang := "", "eng" // the default language
+	var private []byte
+	video := VideoTrack{}
+	audio := AudioTrack{Channels: 1}
+
+	err := ebmlChildren(r, entry.data, end(entry), func(el ebmlElement) (bool, error) {
+		var err error
+		switch el.id {
+		case mkvTrackType:
+			typ, err = readUint(r, el)
+		case mkvCodecID:
+			codec, err = readString(r, el)
+		case mkvCodecPrivate:
+			private, err = readBytes(r, el)
+		case mkvLanguage:
+			lang, err = readString(r, el)
+		case mkvVideo:
+			err = ebmlChildren(r, el.data, end(el), func(el ebmlElement) (bool, error) {
+				var err error
+				var v uint64
+				switch el.id {
+				case mkvPixelWidth:
+					v, err = readUint(r, el)
+					video.Width = int(v)
+				case mkvPixelHeight:
+					v, err = readUint(r, el)
+					video.Height = int(v)
+				case mkvColour:
+					err = ebmlChildren(r, el.data, end(el), func(el ebmlElement) (bool, error) {
+						if el.id == mkvBitsPerChannel {
+							v, err := readUint(r, el)
+							video.ColorDepth = int(v)
+							return true, err
+						}
+						return true, nil
+					})
+				}
+				return true, err
+			})
+		case mkvAudio:
+			err = ebmlChildren(r, el.data, end(el), func(el ebmlElement) (bool, error) {
+				if el.id == mkvChannels {
+					v, err := readUint(r, el)
+					audio.Channels = int(v)
+					return true, err
+				}
+				return true, nil
+			})
+		}
+		return true, err
+	})
+	if err != nil {
+		return err
+	}
+
+	if lang == "und" {
+		lang = ""
+	}
+
+	switch typ {
+	case mkvTrackVideo:
+		video.Codec = codec
+		if video.ColorDepth == 0 {
+			switch codec {
+			case "V_MPEG4/ISO/AVC":
+				video.ColorDepth = avcColorDepth(private)
+			case "V_MPEGH/ISO/HEVC":
+				video.ColorDepth = hevcColorDepth(private)
+			}
+		}
+		info.Video = append(info.Video, video)
+	case mkvTrackAudio:
+		audio.Codec, audio.Language = codec, lang
+		info.Audio = append(info.Audio, audio)
+	case mkvTrackSubtitle:
+		info.Subtitles = append(info.Subtitles, SubtitleTrack{Codec: codec, Language: lang})
+	}
+	return nil
+}
+
+// Guesses the color depth from an AVCDecoderConfigurationRecord.
+// Reading the real depth needs parsing the SPS, so this only looks at the
+// profile: High 10 and up are assumed to be 10-bit.
+func avcColorDepth(avcC []byte) int {
+	if len(avcC) < 2 {
+		return 0
+	}
+	switch avcC[1] {
+	case 110, 122, 244:
+		return 10
+	}
+	return 8
+}
+
+// Reads the color depth from an HEVCDecoderConfigurationRecord.
+func hevcColorDepth(hvcC []byte) int {
+	if len(hvcC) < 18 {
+		return 0
+	}
+	return int(hvcC[17]&0x07) + 8
+}
@@ -0,0 +1,224 @@
+package probe
+
+import (
+	"encoding/binary"
+	"io"
+	"time"
+)
+
+type mp4Box struct {
+	typ  string
+	data int64 // offset of the box's contents
+	end  int64 // -1 if the box extends to EOF
+}
+
+// Calls fn for every box in [start, end). If end is negative, reads until EOF.
+func mp4Boxes(r io.ReadSeeker, start, end int64, fn func(box mp4Box) error) error {
+	pos := start
+	header := make([]byte, 16)
+	for end < 0 || pos+8 <= end {
+		if _, err := r.Seek(pos, 0); err != nil {
+			return err
+		}
+		if _, err := io.ReadFull(r, header[:8]); err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		size := int64(binary.BigEndian.Uint32(header))
+		box := mp4Box{typ: string(header[4:8]), data: pos + 8}
+		switch size {
+		case 0:
+			box.end = end
+		case 1:
+			if _, err := io.ReadFull(r, header[8:16]); err != nil {
+				return err
+			}
+			size = int64(binary.BigEndian.Uint64(header[8:]))
+			box.data += 8
+			box.end = pos + size
+		default:
+			box.end = pos + size
+		}
+		if box.end >= 0 && box.end < box.data {
+			return malformedError
+		}
+
+		if err := fn(box); err != nil {
+			return err
+		}
+		if box.end < 0 {
+			return nil
+		}
+		pos = box.end
+	}
+	return nil
+}
+
+func readBox(r io.ReadSeeker, box mp4Box, max int) ([]byte, error) {
+	if _, err := r.Seek(box.data, 0); err != nil {
+		return nil, err
+	}
+	n := int64(max)
+	if box.end >= 0 && box.end-box.data < n {
+		n = box.end - box.data
+	}
+	b := make([]byte, n)
+	_, err := io.ReadFull(r, b)
+	return b, err
+}
+
+// Reads the timescale and duration from a mvhd or mdhd box.
+func mp4Duration(b []byte) (time.Duration, bool) {
+	var scale, duration uint64
+	switch {
+	case len(b) >= 32 && b[0] == 1:
+		scale = uint64(binary.BigEndian.Uint32(b[20:]))
+		duration = binary.BigEndian.Uint64(b[24:])
+	case len(b) >= 20 && b[0] == 0:
+		scale = uint64(binary.BigEndian.Uint32(b[12:]))
+		duration = uint64(binary.BigEndian.Uint32(b[16:]))
+	default:
+		return 0, false
+	}
+	if scale == 0 {
+		return 0, false
+	}
+	return time.Duration(float64(duration) / float64(scale) * float64(time.Second)), true
+}
+
+// Reads the packed ISO 639-2/T language code from a mdhd box.
+func mp4Language(b []byte) string {
+	off := 20
+	if len(b) > 0 && b[0] == 1 {
+		off = 32
+	}
+	if len(b) < off+2 {
+		return ""
+	}
+	packed := binary.BigEndian.Uint16(b[off:])
+	lang := []byte{
+		byte(packed>>10&0x1F) + 0x60,
+		byte(packed>>5&0x1F) + 0x60,
+		byte(packed&0x1F) + 0x60,
+	}
+	if s := string(lang); s != "und" && packed != 0 {
+		return s
+	}
+	return ""
+}
+
+type mp4Track struct {
+	handler  string
+	language string
+	stsd     []byte
+	stsdBox  mp4Box
+}
+
+func probeMP4(r io.ReadSeeker) (*Info, error) {
+	info := &Info{Format: "mp4"}
+
+	err := mp4Boxes(r, 0, -1, func(box mp4Box) error {
+		if box.typ != "moov" {
+			return nil
+		}
+		return mp4Boxes(r, box.data, box.end, func(box mp4Box) error {
+			switch box.typ {
+			case "mvhd":
+				b, err := readBox(r, box, 32)
+				if d, ok := mp4Duration(b); ok {
+					info.Duration = d
+				}
+				return err
+			case "trak":
+				t := &mp4Track{}
+				if err := t.read(r, box); err != nil {
+					return err
+				}
+				return info.addMP4Track(r, t)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// Reads the interesting parts of a trak box.
+func (t *mp4Track) read(r io.ReadSeeker, trak mp4Box) error {
+	var walk func(box mp4Box) error
+	walk = func(box mp4Box) error {
+		switch box.typ {
+		case "mdia", "minf", "stbl":
+			return mp4Boxes(r, box.data, box.end, walk)
+		case "mdhd":
+			b, err := readBox(r, box, 34)
+			t.language = mp4Language(b)
+			return err
+		case "hdlr":
+			b, err := readBox(r, box, 12)
+			if len(b) == 12 {
+				t.handler = string(b[8:12])
+			}
+			return err
+		case "stsd":
+			t.stsdBox = box
+			b, err := readBox(r, box, 8+8+78)
+			t.stsd = b
+			return err
+		}
+		return nil
+	}
+	return mp4Boxes(r, trak.data, trak.end, walk)
+}
+
+func (info *Info) addMP4Track(r io.ReadSeeker, t *mp4Track) error {
+	// full box header (4) + entry count (4), then the first sample entry
+	if len(t.stsd) < 16 {
+		return nil
+	}
+	entry := t.stsd[8:]
+	format := string(entry[4:8])
+	payload := entry[8:]
+
+	switch t.handler {
+	case "vide":
+		v := VideoTrack{Codec: format}
+		if len(payload) >= 28 {
+			v.Width = int(binary.BigEndian.Uint16(payload[24:]))
+			v.Height = int(binary.BigEndian.Uint16(payload[26:]))
+		}
+		// the codec configuration is a child of the sample entry
+		entryStart := t.stsdBox.data + 8
+		entryEnd := entryStart + int64(binary.BigEndian.Uint32(entry))
+		err := mp4Boxes(r, entryStart+8+78, entryEnd, func(box mp4Box) error {
+			switch box.typ {
+			case "avcC":
+				b, err := readBox(r, box, 2)
+				v.ColorDepth = avcColorDepth(b)
+				return err
+			case "hvcC":
+				b, err := readBox(r, box, 18)
+				v.ColorDepth = hevcColorDepth(b)
+				return err
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		info.Video = append(info.Video, v)
+	case "soun":
+		a := AudioTrack{Codec: format, Language: t.language}
+		if len(payload) >= 18 {
+			a.Channels = int(binary.BigEndian.Uint16(payload[16:]))
+		}
+		info.Audio = append(info.Audio, a)
+	case "sbtl", "subt", "text":
+		info.Subtitles = append(info.Subtitles, SubtitleTrack{Codec: format, Language: t.language})
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+// Reads the headers of local Matroska (MKV, WebM) and MP4 files, to find
+// out about their streams without AniDB's help.
+//
+// Only container headers are read; codecs are reported as the container
+// identifies them (as in "V_MPEG4/ISO/AVC" or "avc1").
+package probe
+
+import (
+	"errors"
+	"io"
+	"os"
+	"time"
+)
+
+var UnknownFormatError = errors.New("probe: unknown container format")
+
+type Info struct {
+	Format   string // "matroska", "webm" or "mp4"
+	Duration time.Duration
+
+	Video     []VideoTrack
+	Audio     []AudioTrack
+	Subtitles []SubtitleTrack
+}
+
+type VideoTrack struct {
+	Codec      string
+	Width      int
+	Height     int
+	ColorDepth int // Bits per channel; 0 if unknown
+}
+
+type AudioTrack struct {
+	Codec    string
+	Language string // ISO 639-2, as in "jpn"; empty if unknown
+	Channels int
+}
+
+type SubtitleTrack struct {
+	Codec    string
+	Language string // ISO 639-2, as in "eng"; empty if unknown
+}
+
+// Probes the file at the given path.
+func File(path string) (*Info, error) {
+	fh, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+
+	return Reader(fh)
+}
+
+// Probes the file in the reader, detecting the format from the first bytes.
+func Reader(r io.ReadSeeker) (*Info, error) {
+	magic := make([]byte, 8)
+	if _, err := io.ReadFull(r, magic); err != nil {
+		return nil, UnknownFormatError
+	}
+	if _, err := r.Seek(0, 0); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case magic[0] == 0x1A && magic[1] == 0x45 && magic[2] == 0xDF && magic[3] == 0xA3:
+		return probeMatroska(r)
+	case string(magic[4:8]) == "ftyp":
+		return probeMP4(r)
+	}
+	return nil, UnknownFormatError
+}
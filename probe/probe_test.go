@@ -0,0 +1,161 @@
+package probe
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+)
+
+// Encodes an EBML element; the id is given with its length marker.
+func ebml(id uint32, data ...[]byte) []byte {
+	b := []byte{}
+	for shift := uint(24); shift > 0; shift -= 8 {
+		if c := byte(id >> shift); c != 0 || len(b) > 0 {
+			b = append(b, c)
+		}
+	}
+	b = append(b, byte(id))
+
+	body := bytes.Join(data, nil)
+	size := make([]byte, 8)
+	binary.BigEndian.PutUint64(size, uint64(len(body)))
+	size[0] = 0x01 // 8 byte size
+	return append(append(b, size...), body...)
+}
+
+func ebmlUint(id uint32, v uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, v)
+	return ebml(id, b)
+}
+
+func ebmlFloat(id uint32, v float64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, math.Float64bits(v))
+	return ebml(id, b)
+}
+
+func ebmlString(id uint32, s string) []byte {
+	return ebml(id, []byte(s))
+}
+
+func TestMatroska(T *testing.T) {
+	T.Parallel()
+
+	file := bytes.Join([][]byte{
+		ebml(ebmlHeader, ebmlString(ebmlDocType, "matroska")),
+		ebml(mkvSegment,
+			ebml(mkvInfo,
+				ebmlUint(mkvTimecodeScale, 1000000),
+				ebmlFloat(mkvDuration, 1440500)),
+			ebml(mkvTracks,
+				ebml(mkvTrackEntry,
+					ebmlUint(mkvTrackType, mkvTrackVideo),
+					ebmlString(mkvCodecID, "V_MPEG4/ISO/AVC"),
+					ebml(mkvCodecPrivate, []byte{1, 110, 0, 40}),
+					ebml(mkvVideo,
+						ebmlUint(mkvPixelWidth, 1920),
+						ebmlUint(mkvPixelHeight, 1080))),
+				ebml(mkvTrackEntry,
+					ebmlUint(mkvTrackType, mkvTrackAudio),
+					ebmlString(mkvCodecID, "A_AAC"),
+					ebmlString(mkvLanguage, "jpn"),
+					ebml(mkvAudio, ebmlUint(mkvChannels, 2))),
+				ebml(mkvTrackEntry,
+					ebmlUint(mkvTrackType, mkvTrackSubtitle),
+					ebmlString(mkvCodecID, "S_TEXT/ASS"))),
+			ebml(mkvCluster, []byte{0, 0, 0, 0})),
+	}, nil)
+
+	info, err := Reader(bytes.NewReader(file))
+	if err != nil {
+		T.Fatal(err)
+	}
+
+	if info.Format != "matroska" {
+		T.Error("Wrong format:", info.Format)
+	}
+	if exp := 1440500 * time.Millisecond; info.Duration != exp {
+		T.Error("Expected duration", exp, "got", info.Duration)
+	}
+	if exp := (VideoTrack{Codec: "V_MPEG4/ISO/AVC", Width: 1920, Height: 1080, ColorDepth: 10}); len(info.Video) != 1 || info.Video[0] != exp {
+		T.Error("Expected video", exp, "got", info.Video)
+	}
+	if exp := (AudioTrack{Codec: "A_AAC", Language: "jpn", Channels: 2}); len(info.Audio) != 1 || info.Audio[0] != exp {
+		T.Error("Expected audio", exp, "got", info.Audio)
+	}
+	if exp := (SubtitleTrack{Codec: "S_TEXT/ASS", Language: "eng"}); len(info.Subtitles) != 1 || info.Subtitles[0] != exp {
+		T.Error("Expected subtitles", exp, "got", info.Subtitles)
+	}
+}
+
+func box(typ string, data ...[]byte) []byte {
+	body := bytes.Join(data, nil)
+	b := make([]byte, 8, 8+len(body))
+	binary.BigEndian.PutUint32(b, uint32(8+len(body)))
+	copy(b[4:], typ)
+	return append(b, body...)
+}
+
+func u16(v uint16) []byte { b := make([]byte, 2); binary.BigEndian.PutUint16(b, v); return b }
+func u32(v uint32) []byte { b := make([]byte, 4); binary.BigEndian.PutUint32(b, v); return b }
+
+func mdhd(lang string) []byte {
+	packed := uint16(lang[0]-0x60)<<10 | uint16(lang[1]-0x60)<<5 | uint16(lang[2]-0x60)
+	return box("mdhd", u32(0), u32(0), u32(0), u32(1000), u32(60000), u16(packed), u16(0))
+}
+
+func hdlr(handler string) []byte {
+	return box("hdlr", u32(0), u32(0), []byte(handler), make([]byte, 12))
+}
+
+func stsd(entry []byte) []byte {
+	return box("stsd", u32(0), u32(1), entry)
+}
+
+func TestMP4(T *testing.T) {
+	T.Parallel()
+
+	visual := bytes.Join([][]byte{make([]byte, 24), u16(1280), u16(720), make([]byte, 50)}, nil)
+	sound := bytes.Join([][]byte{make([]byte, 16), u16(6), make([]byte, 10)}, nil)
+
+	file := bytes.Join([][]byte{
+		box("ftyp", []byte("isom"), u32(0)),
+		box("mdat", make([]byte, 64)),
+		box("moov",
+			box("mvhd", u32(0), u32(0), u32(0), u32(1000), u32(90000)),
+			box("trak", box("mdia", mdhd("und"), hdlr("vide"),
+				box("minf", box("stbl", stsd(box("hvc1", visual,
+					box("hvcC", make([]byte, 17), []byte{0xFA}))))))),
+			box("trak", box("mdia", mdhd("jpn"), hdlr("soun"),
+				box("minf", box("stbl", stsd(box("mp4a", sound))))))),
+	}, nil)
+
+	info, err := Reader(bytes.NewReader(file))
+	if err != nil {
+		T.Fatal(err)
+	}
+
+	if info.Format != "mp4" {
+		T.Error("Wrong format:", info.Format)
+	}
+	if exp := 90 * time.Second; info.Duration != exp {
+		T.Error("Expected duration", exp, "got", info.Duration)
+	}
+	if exp := (VideoTrack{Codec: "hvc1", Width: 1280, Height: 720, ColorDepth: 10}); len(info.Video) != 1 || info.Video[0] != exp {
+		T.Error("Expected video", exp, "got", info.Video)
+	}
+	if exp := (AudioTrack{Codec: "mp4a", Language: "jpn", Channels: 6}); len(info.Audio) != 1 || info.Audio[0] != exp {
+		T.Error("Expected audio", exp, "got", info.Audio)
+	}
+}
+
+func TestUnknown(T *testing.T) {
+	T.Parallel()
+
+	if _, err := Reader(bytes.NewReader([]byte("not a video file"))); err != UnknownFormatError {
+		T.Error("Expected UnknownFormatError, got", err)
+	}
+}
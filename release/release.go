@@ -0,0 +1,115 @@
+// Parses typical fansub release filenames, such as
+//
+//	[Group] Title - 05v2 (1080p) [ABCD1234].mkv
+//
+// into the information they carry.
+package release
+
+import (
+	"github.com/Kovensky/go-anidb/misc"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// The information extracted from a release filename. Fields that
+// couldn't be found are left empty.
+type Info struct {
+	Group   string
+	Title   string
+	Episode *misc.Episode
+
+	Version    int    // 0 if not given; releases without version are version 1
+	Resolution int    // Vertical resolution, as in 720 for "720p"
+	CRC32      string // Lowercase, like File.CRC32
+
+	Extension string // Without the dot
+}
+
+var (
+	extRegexp     = regexp.MustCompile(`\.([0-9A-Za-z]{1,5})$`)
+	groupRegexp   = regexp.MustCompile(`^\s*[\[(]([^\])]+)[\])]`)
+	tagRegexp     = regexp.MustCompile(`[\[(]([^\])]*)[\])]`)
+	crcRegexp     = regexp.MustCompile(`^[0-9A-Fa-f]{8}$`)
+	resRegexp     = regexp.MustCompile(`(?i)\b(?:\d{3,4}x(\d{3,4})|(\d{3,4})[pi])\b`)
+	versionRegexp = regexp.MustCompile(`^(.*?)v(\d+)$`)
+
+	// "Title - 05", "Title - S2v2", "Title - 01-02"
+	dashEpRegexp = regexp.MustCompile(`^(.*?)\s+-\s+([SCTPO]?\d+(?:\.\d+)?(?:v\d+)?)(?:-\d+)?(?:\s+-\s+.*|\s+.*)?$`)
+	// "Title 05", "Title EP05", "Title #05"
+	endEpRegexp = regexp.MustCompile(`(?i)^(.*?)\s+(?:ep?|#)?(\d+(?:v\d+)?)(?:\s+end)?$`)
+)
+
+// Parses the given filename. Directories in the path are ignored.
+func Parse(name string) *Info {
+	info := &Info{}
+	name = filepath.Base(name)
+
+	if m := extRegexp.FindStringSubmatch(name); m != nil {
+		info.Extension = strings.ToLower(m[1])
+		name = name[:len(name)-len(m[0])]
+	}
+
+	if !strings.Contains(name, " ") {
+		if strings.Contains(name, "_") {
+			name = strings.Replace(name, "_", " ", -1)
+		} else {
+			name = strings.Replace(name, ".", " ", -1)
+		}
+	}
+
+	if m := groupRegexp.FindStringSubmatchIndex(name); m != nil {
+		info.Group = strings.TrimSpace(name[m[2]:m[3]])
+		name = name[m[1]:]
+	}
+
+	// the remaining tags carry metadata
+	for _, m := range tagRegexp.FindAllStringSubmatch(name, -1) {
+		for _, tag := range strings.Fields(m[1]) {
+			info.parseTag(tag)
+		}
+	}
+	name = tagRegexp.ReplaceAllString(name, " ")
+
+	// resolutions outside of tags
+	if m := resRegexp.FindStringSubmatchIndex(name); m != nil {
+		info.parseTag(name[m[0]:m[1]])
+		name = name[:m[0]] + name[m[1]:]
+	}
+	name = strings.Join(strings.Fields(name), " ")
+
+	ep := ""
+	if m := dashEpRegexp.FindStringSubmatch(name); m != nil {
+		info.Title, ep = m[1], m[2]
+	} else if m := endEpRegexp.FindStringSubmatch(name); m != nil {
+		info.Title, ep = m[1], m[2]
+	} else {
+		info.Title = name
+	}
+	info.Title = strings.TrimSpace(strings.TrimRight(info.Title, " -"))
+
+	if m := versionRegexp.FindStringSubmatch(ep); m != nil {
+		ep = m[1]
+		info.Version, _ = strconv.Atoi(m[2])
+	}
+	if ep != "" {
+		info.Episode = misc.ParseEpisode(ep)
+	}
+
+	return info
+}
+
+func (info *Info) parseTag(tag string) {
+	switch {
+	case crcRegexp.MatchString(tag):
+		info.CRC32 = strings.ToLower(tag)
+	case resRegexp.MatchString(tag):
+		m := resRegexp.FindStringSubmatch(tag)
+		res := m[1]
+		if res == "" {
+			res = m[2]
+		}
+		info.Resolution, _ = strconv.Atoi(res)
+	}
+}
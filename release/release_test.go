@@ -0,0 +1,25 @@
+package release_test
+
+import (
+	"fmt"
+	"github.com/Kovensky/go-anidb/release"
+)
+
+func ExampleParse() {
+	for _, name := range []string{
+		"[Group] Title - 05v2 (1080p) [ABCD1234].mkv",
+		"/mnt/anime/[Some-Group] Long Title: Subtitle - S2 [720p].mp4",
+		"[Group]_Another_Title_-_12_[h264][1280x720][0123ABCD].mkv",
+		"Title 03 END.avi",
+	} {
+		info := release.Parse(name)
+		fmt.Printf("%q %q %v v%d %dp %q %q\n", info.Group, info.Title, info.Episode,
+			info.Version, info.Resolution, info.CRC32, info.Extension)
+	}
+
+	// Output:
+	// "Group" "Title" 5 v2 1080p "abcd1234" "mkv"
+	// "Some-Group" "Long Title: Subtitle" S2 v0 720p "" "mp4"
+	// "Group" "Another Title" 12 v0 720p "0123abcd" "mkv"
+	// "" "Title" 3 v0 0p "" "avi"
+}
@@ -0,0 +1,238 @@
+package rename
+
+import (
+	"fmt"
+	"github.com/Kovensky/go-anidb"
+	"github.com/Kovensky/go-anidb/misc"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// The file being renamed, and its metadata.
+type Item struct {
+	Path string // Current path of the file
+
+	File    *anidb.File
+	Anime   *anidb.Anime
+	Episode *anidb.Episode
+	Group   *anidb.Group
+}
+
+// Returns an Item for the file at the given path, using the cached
+// metadata for the File.
+func NewItem(path string, f *anidb.File) *Item {
+	it := &Item{Path: path, File: f}
+	if f != nil {
+		it.Anime = f.Anime()
+		it.Episode = f.Episode()
+		it.Group = f.Group()
+	}
+	return it
+}
+
+type fieldFunc func(it *Item, arg string) string
+
+var fields = map[string]fieldFunc{
+	"anime.title":    animeTitle,
+	"anime.aid":      animeInt(func(a *anidb.Anime) int { return int(a.AID) }),
+	"anime.episodes": animeInt(func(a *anidb.Anime) int { return a.TotalEpisodes }),
+	"anime.year":     animeInt(func(a *anidb.Anime) int { return a.StartDate.Year() }),
+	"anime.type": func(it *Item, _ string) string {
+		if it.Anime == nil {
+			return ""
+		}
+		return string(it.Anime.Type)
+	},
+
+	"ep.fmt":   episodeFormat,
+	"ep.title": episodeTitle,
+	"ep.eid": func(it *Item, _ string) string {
+		if it.Episode == nil {
+			return ""
+		}
+		return strconv.Itoa(int(it.Episode.EID))
+	},
+
+	"group.name": func(it *Item, _ string) string {
+		if it.Group == nil {
+			return ""
+		}
+		return it.Group.Name
+	},
+	"group.short": func(it *Item, _ string) string {
+		if it.Group == nil {
+			return ""
+		}
+		return it.Group.ShortName
+	},
+	"group.gid": func(it *Item, _ string) string {
+		if it.Group == nil {
+			return ""
+		}
+		return strconv.Itoa(int(it.Group.GID))
+	},
+
+	"file.fid": fileField(func(f *anidb.File, _ string) string { return strconv.Itoa(int(f.FID)) }),
+	"file.version": fileField(func(f *anidb.File, _ string) string {
+		if f.Version < 2 {
+			return ""
+		}
+		return f.Version.String()
+	}),
+	"file.source": fileField(func(f *anidb.File, _ string) string { return string(f.Source) }),
+	"crc32": fileField(func(f *anidb.File, arg string) string {
+		if arg == "lower" {
+			return strings.ToLower(f.CRC32)
+		}
+		return strings.ToUpper(f.CRC32)
+	}),
+	"ed2k": fileField(func(f *anidb.File, _ string) string { return f.Ed2kHash }),
+
+	"video.res": fileField(func(f *anidb.File, _ string) string {
+		if h := f.VideoInfo.Resolution.Dy(); h > 0 {
+			return fmt.Sprintf("%dp", h)
+		}
+		return ""
+	}),
+	"video.codec": fileField(func(f *anidb.File, _ string) string { return f.VideoInfo.Codec }),
+	"audio.codec": fileField(func(f *anidb.File, _ string) string {
+		if len(f.AudioStreams) == 0 {
+			return ""
+		}
+		return f.AudioStreams[0].Codec
+	}),
+	"audio.lang": fileField(func(f *anidb.File, _ string) string {
+		if len(f.AudioStreams) == 0 {
+			return ""
+		}
+		return string(f.AudioStreams[0].Language)
+	}),
+
+	"ext": func(it *Item, _ string) string {
+		if ext := filepath.Ext(it.Path); ext != "" {
+			return ext
+		}
+		if it.File != nil && it.File.FileExtension != "" {
+			return "." + it.File.FileExtension
+		}
+		return ""
+	},
+}
+
+func languages(arg string) []anidb.Language {
+	langs := []anidb.Language{}
+	for _, l := range strings.Split(arg, ",") {
+		if l = strings.TrimSpace(l); l != "" {
+			langs = append(langs, anidb.Language(l))
+		}
+	}
+	return langs
+}
+
+func animeTitle(it *Item, arg string) string {
+	a := it.Anime
+	if a == nil {
+		return ""
+	}
+	for _, l := range languages(arg) {
+		if t := a.OfficialTitles[l]; t != "" {
+			return t
+		}
+		// the romanized title is usually only the primary title
+		if l == "x-jat" {
+			return a.PrimaryTitle
+		}
+	}
+	return a.PrimaryTitle
+}
+
+func animeInt(fn func(a *anidb.Anime) int) fieldFunc {
+	return func(it *Item, _ string) string {
+		if it.Anime == nil {
+			return ""
+		}
+		if i := fn(it.Anime); i > 0 {
+			return strconv.Itoa(i)
+		}
+		return ""
+	}
+}
+
+func fileField(fn func(f *anidb.File, arg string) string) fieldFunc {
+	return func(it *Item, arg string) string {
+		if it.File == nil {
+			return ""
+		}
+		return fn(it.File, arg)
+	}
+}
+
+// Formats the file's episodes, which may be a list (as in "01-02") or
+// not be regular episodes (as in "S1" for a special).
+//
+// Openings and endings use the file's EpisodeString as-is (as in "OP1"),
+// as does anything that can't be parsed as an episode list.
+func episodeFormat(it *Item, arg string) string {
+	var el misc.EpisodeList
+	switch {
+	case it.File != nil && it.File.EpisodeString != "" &&
+		(len(misc.ParseEpisodeList(it.File.EpisodeString)) == 0 || hasCredits(it.File.EpisodeNumber)):
+		return it.File.EpisodeString
+	case it.File != nil && len(it.File.EpisodeNumber) > 0:
+		el = it.File.EpisodeNumber
+	case it.File != nil && it.File.EpisodeString != "":
+		el = misc.ParseEpisodeList(it.File.EpisodeString)
+	case it.Episode != nil:
+		el = misc.EpisodeToList(&it.Episode.Episode)
+	default:
+		return ""
+	}
+
+	width, err := strconv.Atoi(arg)
+	if err != nil || width < 1 {
+		width = 1
+		if it.Anime != nil {
+			width = len(strconv.Itoa(it.Anime.TotalEpisodes))
+		}
+	}
+
+	parts := make([]string, 0, len(el))
+	for _, er := range el {
+		if er == nil {
+			continue
+		}
+		if er.Type == misc.EpisodeTypeRegular {
+			parts = append(parts, er.Format(width))
+		} else {
+			parts = append(parts, er.Format(1))
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+func hasCredits(el misc.EpisodeList) bool {
+	for _, er := range el {
+		if er != nil && er.Type == misc.EpisodeTypeCredits {
+			return true
+		}
+	}
+	return false
+}
+
+func episodeTitle(it *Item, arg string) string {
+	ep := it.Episode
+	if ep == nil {
+		return ""
+	}
+	langs := languages(arg)
+	if len(langs) == 0 {
+		langs = []anidb.Language{"en"}
+	}
+	for _, l := range langs {
+		if t := ep.Titles[l]; t != "" {
+			return t
+		}
+	}
+	return ""
+}
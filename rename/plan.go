@@ -0,0 +1,152 @@
+package rename
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// A single rename.
+type Rename struct {
+	From string
+	To   string
+
+	// Why the rename can't be done; nil if it can.
+	Conflict error `json:"-"`
+}
+
+// Returned as a Rename's Conflict when the target already exists.
+type ExistsError struct {
+	Path string
+}
+
+func (e *ExistsError) Error() string {
+	return fmt.Sprintf("rename: %q already exists", e.Path)
+}
+
+// Returned as a Rename's Conflict when more than one file would be
+// renamed to the same target.
+type DuplicateError struct {
+	Path  string
+	Other string // The other file with the same target
+}
+
+func (e *DuplicateError) Error() string {
+	return fmt.Sprintf("rename: %q would also be renamed to %q", e.Other, e.Path)
+}
+
+// The renames to be done for a set of files. Creating a Plan doesn't
+// touch the filesystem besides checking for existing files, so it can be
+// used as a dry run.
+type Plan struct {
+	Renames []Rename
+}
+
+// Plans the renames of the given items. New names are relative to the
+// directory the file is currently in, unless the template results in an
+// absolute path. Files that already have the right name are skipped.
+func NewPlan(t *Template, items ...*Item) *Plan {
+	p := &Plan{}
+	targets := map[string]string{}
+
+	for _, it := range items {
+		to := t.Execute(it)
+		if !filepath.IsAbs(to) {
+			to = filepath.Join(filepath.Dir(it.Path), to)
+		}
+		to = filepath.Clean(to)
+		from := filepath.Clean(it.Path)
+
+		if from == to {
+			continue
+		}
+
+		r := Rename{From: from, To: to}
+		if other, ok := targets[to]; ok {
+			r.Conflict = &DuplicateError{Path: to, Other: other}
+		} else if _, err := os.Lstat(to); err == nil {
+			r.Conflict = &ExistsError{Path: to}
+		}
+		targets[to] = from
+
+		p.Renames = append(p.Renames, r)
+	}
+	return p
+}
+
+// Returns the renames that have conflicts.
+func (p *Plan) Conflicts() []Rename {
+	c := []Rename{}
+	for _, r := range p.Renames {
+		if r.Conflict != nil {
+			c = append(c, r)
+		}
+	}
+	return c
+}
+
+// Does every rename without conflicts, creating directories as needed.
+// Stops at the first error.
+//
+// Returns a log of the renames that were done, which can be used to
+// undo them, even if there was an error.
+func (p *Plan) Execute() (*UndoLog, error) {
+	log := &UndoLog{Time: time.Now()}
+
+	for _, r := range p.Renames {
+		if r.Conflict != nil {
+			continue
+		}
+		if err := move(r.From, r.To); err != nil {
+			return log, err
+		}
+		log.Renames = append(log.Renames, r)
+	}
+	return log, nil
+}
+
+func move(from, to string) error {
+	if _, err := os.Lstat(to); err == nil {
+		return &ExistsError{Path: to}
+	}
+	if err := os.MkdirAll(filepath.Dir(to), 0755); err != nil {
+		return err
+	}
+	return os.Rename(from, to)
+}
+
+// The renames done by a Plan, in order.
+type UndoLog struct {
+	Time    time.Time
+	Renames []Rename
+}
+
+// Reads an UndoLog saved by Save.
+func ReadUndoLog(r io.Reader) (*UndoLog, error) {
+	log := &UndoLog{}
+	if err := json.NewDecoder(r).Decode(log); err != nil {
+		return nil, err
+	}
+	return log, nil
+}
+
+// Writes the UndoLog as JSON.
+func (log *UndoLog) Save(w io.Writer) error {
+	return json.NewEncoder(w).Encode(log)
+}
+
+// Reverts the renames in reverse order. Directories created by the
+// renames are left in place. Stops at the first error.
+func (log *UndoLog) Undo() error {
+	for i := len(log.Renames) - 1; i >= 0; i-- {
+		r := log.Renames[i]
+		if err := move(r.To, r.From); err != nil {
+			return err
+		}
+		log.Renames = log.Renames[:i]
+	}
+	return nil
+}
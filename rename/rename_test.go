@@ -0,0 +1,127 @@
+package rename
+
+import (
+	"github.com/Kovensky/go-anidb"
+	"github.com/Kovensky/go-anidb/misc"
+	"image"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func testItem(path string) *Item {
+	return &Item{
+		Path: path,
+		File: &anidb.File{
+			FID:           1,
+			EpisodeNumber: misc.ParseEpisodeList("5"),
+			CRC32:         "abcd1234",
+			Version:       2,
+			VideoInfo:     anidb.VideoInfo{Resolution: image.Rect(0, 0, 1280, 720)},
+		},
+		Anime: &anidb.Anime{
+			AID:            1,
+			TotalEpisodes:  26,
+			PrimaryTitle:   "Seikai no Monshou",
+			OfficialTitles: anidb.UniqueTitleMap{"en": "Crest of the Stars"},
+		},
+		Episode: &anidb.Episode{
+			Titles: anidb.UniqueTitleMap{"en": "Bird Cage: Part 1/2"},
+		},
+		Group: &anidb.Group{ShortName: "a-S"},
+	}
+}
+
+func TestTemplate(T *testing.T) {
+	T.Parallel()
+
+	t := MustParse("{anime.title:de,x-jat} - {ep.fmt:2}{file.version} - {ep.title:en} [{group.short}][{video.res}][{crc32}]{ext}")
+	name := t.Execute(testItem("/tmp/file.mkv"))
+	if exp := "Seikai no Monshou - 05v2 - Bird Cage꞉ Part 1∕2 [a-S][720p][ABCD1234].mkv"; name != exp {
+		T.Errorf("Expected %q, got %q", exp, name)
+	}
+
+	name = MustParse("{{{anime.title:en}}} {ep.fmt}").Execute(testItem("x"))
+	if exp := "{Crest of the Stars} 05"; name != exp {
+		T.Errorf("Expected %q, got %q", exp, name)
+	}
+
+	op := testItem("x")
+	op.File.EpisodeNumber = misc.ParseEpisodeList("C1")
+	op.File.EpisodeString = "OP1"
+	if name, exp := MustParse("{ep.fmt:2}").Execute(op), "OP1"; name != exp {
+		T.Errorf("Expected %q, got %q", exp, name)
+	}
+
+	ed := testItem("x")
+	ed.File.EpisodeNumber = misc.ParseEpisodeList("C2")
+	ed.File.EpisodeString = "ED"
+	if name, exp := MustParse("{ep.fmt:2}").Execute(ed), "ED"; name != exp {
+		T.Errorf("Expected %q, got %q", exp, name)
+	}
+
+	sp := testItem("x")
+	sp.File.EpisodeNumber = misc.ParseEpisodeList("S1")
+	sp.File.EpisodeString = "S1"
+	if name, exp := MustParse("{ep.fmt:2}").Execute(sp), "S1"; name != exp {
+		T.Errorf("Expected %q, got %q", exp, name)
+	}
+
+	for _, bad := range []string{"{anime.title", "anime.title}", "{nope}"} {
+		if _, err := Parse(bad); err == nil {
+			T.Errorf("Parsing %q should have failed", bad)
+		}
+	}
+}
+
+func TestPlan(T *testing.T) {
+	T.Parallel()
+
+	dir, err := ioutil.TempDir("", "anidb-rename")
+	if err != nil {
+		T.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	paths := []string{}
+	for _, name := range []string{"a.mkv", "b.mkv", "c.mkv"} {
+		p := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(p, []byte(name), 0644); err != nil {
+			T.Fatal(err)
+		}
+		paths = append(paths, p)
+	}
+
+	a, b := testItem(paths[0]), testItem(paths[1])
+	b.File = &anidb.File{EpisodeNumber: misc.ParseEpisodeList("6")}
+	c := testItem(paths[2]) // same name as a
+
+	plan := NewPlan(MustParse("{anime.title:en}/{ep.fmt}{ext}"), a, b, c)
+	if len(plan.Renames) != 3 {
+		T.Fatal("Expected 3 renames, got", len(plan.Renames))
+	}
+	if conflicts := plan.Conflicts(); len(conflicts) != 1 || conflicts[0].From != paths[2] {
+		T.Fatal("Expected a conflict for", paths[2], "got", conflicts)
+	}
+
+	log, err := plan.Execute()
+	if err != nil {
+		T.Fatal(err)
+	}
+	if len(log.Renames) != 2 {
+		T.Fatal("Expected 2 renames done, got", len(log.Renames))
+	}
+	if _, err := os.Stat(filepath.Join(dir, "Crest of the Stars", "06.mkv")); err != nil {
+		T.Error(err)
+	}
+
+	if err := log.Undo(); err != nil {
+		T.Fatal(err)
+	}
+	for _, p := range paths {
+		if _, err := os.Stat(p); err != nil {
+			T.Error(err)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+// Renames files using AniDB metadata, following a template.
+//
+// Templates are plain text with fields in braces, which are replaced by
+// the matching metadata. Fields may take an argument after a colon:
+//
+//	{anime.title:en,x-jat} - {ep.fmt:2} - {ep.title:en} [{group.short}][{video.res}][{crc32}]{ext}
+//
+// Literal braces are written doubled, as "{{" and "}}". Field values are
+// sanitized for use in file names; slashes in the template itself create
+// directories.
+//
+// Known fields:
+//
+//	anime.title[:langs]  Official title in the first available language;
+//	                     defaults to the primary title
+//	anime.aid            Anime ID
+//	anime.type           Anime type (TV Series, OVA...)
+//	anime.year           Year of the first episode
+//	anime.episodes       Total number of regular episodes
+//	ep.fmt[:width]       Episode number(s), zero-padded to width; defaults to
+//	                     the width needed by the anime's episode count
+//	ep.title[:langs]     Episode title in the first available language;
+//	                     defaults to English
+//	ep.eid               Episode ID
+//	group.name           Group name
+//	group.short          Group short name
+//	group.gid            Group ID
+//	file.fid             File ID
+//	file.version         File version ("v2", "v3"...); empty for version 1
+//	file.source          File source (TV, DVD, www...)
+//	crc32[:lower]        CRC32, uppercase by default
+//	ed2k                 ed2k hash
+//	video.res            Vertical resolution, as in "720p"
+//	video.codec          Video codec
+//	audio.codec          Codec of the first audio stream
+//	audio.lang           Language of the first audio stream
+//	ext                  Extension of the original file, including the dot
+package rename
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A piece of a template; either literal text or a field.
+type part struct {
+	text  string
+	field fieldFunc
+	arg   string
+}
+
+// A parsed template.
+type Template struct {
+	source string
+	parts  []part
+}
+
+func (t *Template) String() string {
+	return t.source
+}
+
+// Parses a template. Fails on unknown fields and unbalanced braces.
+func Parse(s string) (*Template, error) {
+	t := &Template{source: s}
+
+	text := []byte{}
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '{' && i+1 < len(s) && s[i+1] == '{',
+			c == '}' && i+1 < len(s) && s[i+1] == '}':
+			text = append(text, c)
+			i++
+		case c == '}':
+			return nil, fmt.Errorf("rename: unbalanced } at offset %d", i)
+		case c == '{':
+			end := strings.IndexByte(s[i:], '}')
+			if end < 0 {
+				return nil, fmt.Errorf("rename: unterminated field at offset %d", i)
+			}
+			name, arg := s[i+1:i+end], ""
+			if colon := strings.IndexByte(name, ':'); colon >= 0 {
+				name, arg = name[:colon], name[colon+1:]
+			}
+
+			fn, ok := fields[name]
+			if !ok {
+				return nil, fmt.Errorf("rename: unknown field %q", name)
+			}
+
+			if len(text) > 0 {
+				t.parts = append(t.parts, part{text: string(text)})
+				text = text[:0]
+			}
+			t.parts = append(t.parts, part{field: fn, arg: arg})
+			i += end
+		default:
+			text = append(text, c)
+		}
+	}
+	if len(text) > 0 {
+		t.parts = append(t.parts, part{text: string(text)})
+	}
+
+	return t, nil
+}
+
+// Like Parse, but panics on errors.
+func MustParse(s string) *Template {
+	t, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// Returns the new name (possibly including directories) for the item.
+// Missing metadata results in empty fields.
+func (t *Template) Execute(it *Item) string {
+	b := make([]string, len(t.parts))
+	for i, p := range t.parts {
+		if p.field != nil {
+			b[i] = Sanitize(p.field(it, p.arg))
+		} else {
+			b[i] = p.text
+		}
+	}
+	return strings.Join(b, "")
+}
+
+var sanitizer = strings.NewReplacer(
+	"/", "∕",
+	"\\", "⧵",
+	":", "꞉",
+	"*", "∗",
+	"?", "？",
+	"\"", "''",
+	"<", "‹",
+	">", "›",
+	"|", "ǀ",
+)
+
+// Replaces characters that aren't allowed in file names on common
+// filesystems with lookalikes, and strips control characters and
+// trailing dots and spaces.
+func Sanitize(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return -1
+		}
+		return r
+	}, sanitizer.Replace(s))
+	return strings.TrimRight(s, ". ")
+}
@@ -0,0 +1,114 @@
+package anidb
+
+import (
+	"sync"
+	"time"
+)
+
+type scrobblePathLock struct {
+	sync.Mutex
+	users int // goroutines holding or waiting for the lock
+}
+
+var scrobbleLocks = struct {
+	sync.Mutex
+	m map[string]*scrobblePathLock
+}{m: map[string]*scrobblePathLock{}}
+
+// Locks the path; the lock is forgotten once the last user unlocks it.
+func lockScrobblePath(path string) {
+	scrobbleLocks.Lock()
+	l := scrobbleLocks.m[path]
+	if l == nil {
+		l = &scrobblePathLock{}
+		scrobbleLocks.m[path] = l
+	}
+	l.users++
+	scrobbleLocks.Unlock()
+
+	l.Lock()
+}
+
+func unlockScrobblePath(path string) {
+	scrobbleLocks.Lock()
+	defer scrobbleLocks.Unlock()
+
+	l := scrobbleLocks.m[path]
+	l.users--
+	if l.users == 0 {
+		delete(scrobbleLocks.m, path)
+	}
+	l.Unlock()
+}
+
+// Reports that the file at the given path has been played up to position,
+// out of duration. Meant to be called repeatedly by media player plugins.
+//
+// Once position reaches adb.ScrobbleThreshold of the duration, the file is
+// identified by its ed2k hash and marked as watched in the mylist, being
+// added to it (with the HDD state) if needed. Files that are already marked
+// as watched aren't touched, so repeated reports don't cause further API
+// requests.
+//
+// Returns whether the file is marked as watched. Uses the UDP API.
+func (adb *AniDB) ScrobblePath(path string, position, duration time.Duration) <-chan bool {
+	ch := make(chan bool, 1)
+
+	if duration <= 0 || float64(position) < adb.ScrobbleThreshold*float64(duration) {
+		ch <- false
+		close(ch)
+		return ch
+	}
+
+	go func() {
+		ch <- adb.scrobble(path)
+		close(ch)
+	}()
+	return ch
+}
+
+func (adb *AniDB) scrobble(path string) bool {
+	lockScrobblePath(path)
+	defer unlockScrobblePath(path)
+
+	user := <-adb.GetCurrentUser()
+	if user == nil || user.UID < 1 {
+		return false
+	}
+
+	h, err := HashFile(path)
+	if err != nil {
+		return false
+	}
+
+	f := <-adb.FileByEd2kSize(h.Ed2k, h.Size)
+	if f == nil {
+		return false
+	}
+
+	if e := f.UserMyList(user); e != nil && !e.DateWatched.IsZero() {
+		return true
+	}
+
+	now := time.Now()
+	watched := true
+	set := &MyListSet{Watched: &watched, ViewDate: &now}
+
+	if f.LID[user.UID] < 1 {
+		state := MyListStateHDD
+		add := *set
+		add.State = &state
+
+		lid := <-adb.MyListAdd(f, &add)
+		if lid < 1 {
+			return false
+		}
+		// the file may have already been in the mylist without us knowing,
+		// in which case the add didn't change anything
+		if e := lid.MyListEntry(); e != nil && !e.DateWatched.IsZero() {
+			return true
+		}
+	}
+
+	return <-adb.MyListEdit(f, set)
+}
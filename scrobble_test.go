@@ -0,0 +1,25 @@
+package anidb
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestScrobblePathLocks(T *testing.T) {
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			lockScrobblePath("/some/file.mkv")
+			unlockScrobblePath("/some/file.mkv")
+		}()
+	}
+	wg.Wait()
+
+	scrobbleLocks.Lock()
+	defer scrobbleLocks.Unlock()
+	if len(scrobbleLocks.m) != 0 {
+		T.Errorf("Expected no locks left, got %d", len(scrobbleLocks.m))
+	}
+}
@@ -0,0 +1,207 @@
+package anidb
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// How many points each preference is worth.
+type SelectorWeights struct {
+	Resolution        float64 // For the most preferred resolution
+	Codec             float64 // For the most preferred codec
+	ColorDepth        float64 // For the most preferred color depth
+	AudioLanguage     float64 // For the most preferred audio language
+	SubtitleLanguage  float64 // For the most preferred subtitle language
+	GroupRating       float64 // Per point of the group's rating (0-10)
+	Version           float64 // Per version above 1
+	DeprecatedPenalty float64
+	BadCRCPenalty     float64
+}
+
+var DefaultSelectorWeights = SelectorWeights{
+	Resolution:        10,
+	Codec:             5,
+	ColorDepth:        3,
+	AudioLanguage:     8,
+	SubtitleLanguage:  8,
+	GroupRating:       1,
+	Version:           2,
+	DeprecatedPenalty: 20,
+	BadCRCPenalty:     5,
+}
+
+// Scores candidate Files according to preferences, so that the same
+// release gets picked every time.
+//
+// Preference lists are in order, most preferred first; a file matching the
+// first entry gets the full weight, later entries get proportionally less,
+// and files not matching any entry get nothing.
+type Selector struct {
+	Resolutions       []int    // Vertical resolutions, as in 1080
+	Codecs            []string // Video codecs, matched case-insensitively
+	ColorDepths       []int
+	AudioLanguages    []Language
+	SubtitleLanguages []Language
+
+	// If nil, DefaultSelectorWeights is used.
+	Weights *SelectorWeights
+}
+
+// A File and its score, with the reasons for it.
+type Ranking struct {
+	File    *File
+	Score   float64
+	Reasons []string
+}
+
+func (r *Ranking) add(points float64, format string, args ...interface{}) {
+	if points == 0 {
+		return
+	}
+	r.Score += points
+	r.Reasons = append(r.Reasons, fmt.Sprintf("%+.1f ", points)+fmt.Sprintf(format, args...))
+}
+
+// Returns the points for the position of the first match in a
+// preference list of length n, or 0 if i < 0.
+func preferencePoints(weight float64, i, n int) float64 {
+	if i < 0 || n == 0 {
+		return 0
+	}
+	return weight * float64(n-i) / float64(n)
+}
+
+func indexInt(list []int, v int) int {
+	for i := range list {
+		if list[i] == v {
+			return i
+		}
+	}
+	return -1
+}
+
+// Returns the index of the most preferred language in the list that's in langs.
+func indexLanguage(list []Language, langs []Language) int {
+	for i := range list {
+		for _, l := range langs {
+			if strings.EqualFold(string(list[i]), string(l)) {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// Scores a single File. The File's Group is read from the cache.
+func (s *Selector) Score(f *File) *Ranking {
+	w := s.Weights
+	if w == nil {
+		w = &DefaultSelectorWeights
+	}
+	r := &Ranking{File: f}
+
+	if res := f.VideoInfo.Resolution.Dy(); res > 0 {
+		i := indexInt(s.Resolutions, res)
+		r.add(preferencePoints(w.Resolution, i, len(s.Resolutions)), "resolution %dp", res)
+	}
+
+	for i, c := range s.Codecs {
+		if strings.EqualFold(c, f.VideoInfo.Codec) {
+			r.add(preferencePoints(w.Codec, i, len(s.Codecs)), "codec %s", f.VideoInfo.Codec)
+			break
+		}
+	}
+
+	if d := f.VideoInfo.ColorDepth; d > 0 {
+		i := indexInt(s.ColorDepths, d)
+		r.add(preferencePoints(w.ColorDepth, i, len(s.ColorDepths)), "%d-bit color", d)
+	}
+
+	audio := make([]Language, len(f.AudioStreams))
+	for i := range f.AudioStreams {
+		audio[i] = f.AudioStreams[i].Language
+	}
+	if i := indexLanguage(s.AudioLanguages, audio); i >= 0 {
+		r.add(preferencePoints(w.AudioLanguage, i, len(s.AudioLanguages)),
+			"audio in %s", s.AudioLanguages[i])
+	}
+
+	if i := indexLanguage(s.SubtitleLanguages, f.SubtitleLanguages); i >= 0 {
+		r.add(preferencePoints(w.SubtitleLanguage, i, len(s.SubtitleLanguages)),
+			"subtitles in %s", s.SubtitleLanguages[i])
+	}
+
+	if g := f.Group(); g != nil && g.Rating.VoteCount > 0 {
+		r.add(w.GroupRating*float64(g.Rating.Rating), "group %s rated %.2f", g.Name, g.Rating.Rating)
+	}
+
+	if f.Version > 1 {
+		r.add(w.Version*float64(f.Version-1), "version %d", f.Version)
+	}
+	if f.Deprecated {
+		r.add(-w.DeprecatedPenalty, "deprecated")
+	}
+	if f.BadCRC {
+		r.add(-w.BadCRCPenalty, "bad CRC")
+	}
+
+	return r
+}
+
+type rankings []*Ranking
+
+func (r rankings) Len() int           { return len(r) }
+func (r rankings) Less(i, j int) bool { return r[i].Score > r[j].Score }
+func (r rankings) Swap(i, j int)      { r[i], r[j] = r[j], r[i] }
+
+// Scores the Files and returns them best first. Ties keep the
+// given order. nil Files are skipped.
+func (s *Selector) Rank(files []*File) []*Ranking {
+	r := make(rankings, 0, len(files))
+	for _, f := range files {
+		if f != nil {
+			r = append(r, s.Score(f))
+		}
+	}
+	sort.Stable(r)
+	return r
+}
+
+// Returns the best of the Files, or nil if there are none.
+func (s *Selector) Best(files []*File) *File {
+	if r := s.Rank(files); len(r) > 0 {
+		return r[0].File
+	}
+	return nil
+}
+
+// Ranks all files for the episode, from every group (see FilesForEpisode).
+// The files' groups are retrieved first, so their ratings count.
+// Uses the UDP API.
+func (adb *AniDB) RankFilesForEpisode(ep *Episode, s *Selector) <-chan []*Ranking {
+	ch := make(chan []*Ranking, 1)
+
+	go func() {
+		files := []*File{}
+		for f := range adb.FilesForEpisode(ep) {
+			if f != nil {
+				files = append(files, f)
+			}
+		}
+
+		gids := map[GID]<-chan *Group{}
+		for _, f := range files {
+			if _, ok := gids[f.GID]; !ok && f.GID > 0 {
+				gids[f.GID] = adb.GroupByID(f.GID)
+			}
+		}
+		for _, gch := range gids {
+			<-gch
+		}
+
+		ch <- s.Rank(files)
+		close(ch)
+	}()
+	return ch
+}
@@ -42,11 +42,15 @@ type udpWrap struct {
 
 	sendLock    sync.Mutex
 	sendQueueCh chan paramSet
+	lowQueueCh  chan paramSet // only sent from when sendQueueCh is empty
 
 	credLock    sync.Mutex
 	credentials *credentials
 	connected   bool
 
+	notifyLock sync.Mutex
+	notifySubs map[chan udpapi.APIReply]bool
+
 	user *User
 }
 
@@ -55,11 +59,47 @@ func newUDPWrap(adb *AniDB) *udpWrap {
 		AniDBUDP:    udpapi.NewAniDBUDP(),
 		adb:         adb,
 		sendQueueCh: make(chan paramSet, 10),
+		lowQueueCh:  make(chan paramSet, 10),
+		notifySubs:  map[chan udpapi.APIReply]bool{},
 	}
 	go u.sendQueue()
+	go u.dispatchNotifications()
 	return u
 }
 
+// Copies every PUSH notification to every subscriber. Subscribers that
+// aren't keeping up miss notifications instead of blocking the others.
+func (udp *udpWrap) dispatchNotifications() {
+	for n := range udp.AniDBUDP.Notifications {
+		udp.notifyLock.Lock()
+		for ch := range udp.notifySubs {
+			select {
+			case ch <- n:
+			default:
+			}
+		}
+		udp.notifyLock.Unlock()
+	}
+}
+
+// Returns a channel that receives every PUSH notification, and a function
+// that unsubscribes it and returns how many subscribers are left.
+func (udp *udpWrap) subscribeNotifications() (<-chan udpapi.APIReply, func() int) {
+	ch := make(chan udpapi.APIReply, 10)
+
+	udp.notifyLock.Lock()
+	udp.notifySubs[ch] = true
+	udp.notifyLock.Unlock()
+
+	return ch, func() int {
+		udp.notifyLock.Lock()
+		defer udp.notifyLock.Unlock()
+
+		delete(udp.notifySubs, ch)
+		return len(udp.notifySubs)
+	}
+}
+
 type paramMap udpapi.ParamMap // shortcut
 
 type noauthAPIReply struct {
@@ -110,7 +150,17 @@ func (udp *udpWrap) logReply(reply udpapi.APIReply) {
 func (udp *udpWrap) sendQueue() {
 	initialWait := 5 * time.Second
 	wait := initialWait
-	for set := range udp.sendQueueCh {
+	for {
+		var set paramSet
+		select {
+		case set = <-udp.sendQueueCh:
+		default:
+			select {
+			case set = <-udp.sendQueueCh:
+			case set = <-udp.lowQueueCh:
+			}
+		}
+
 	Retry:
 		if Banned() {
 			set.ch <- bannedReply
@@ -162,6 +212,16 @@ func (udp *udpWrap) sendQueue() {
 }
 
 func (udp *udpWrap) SendRecv(cmd string, params paramMap) <-chan udpapi.APIReply {
+	return udp.sendRecv(cmd, params, udp.sendQueueCh)
+}
+
+// Like SendRecv, but the command is only sent when no command sent
+// through SendRecv is waiting, so that bulk operations don't delay others.
+func (udp *udpWrap) SendRecvLowPriority(cmd string, params paramMap) <-chan udpapi.APIReply {
+	return udp.sendRecv(cmd, params, udp.lowQueueCh)
+}
+
+func (udp *udpWrap) sendRecv(cmd string, params paramMap, queue chan paramSet) <-chan udpapi.APIReply {
 	ch := make(chan udpapi.APIReply, 1)
 
 	udp.sendLock.Lock()
@@ -185,7 +245,7 @@ func (udp *udpWrap) SendRecv(cmd string, params paramMap) <-chan udpapi.APIReply
 		params = paramMap{}
 	}
 
-	udp.sendQueueCh <- paramSet{
+	queue <- paramSet{
 		cmd:    cmd,
 		params: params,
 		ch:     ch,
@@ -0,0 +1,123 @@
+package anidb
+
+import (
+	"sync"
+)
+
+// Why a file was suggested as a replacement for an owned file.
+type UpgradeReason int
+
+const (
+	UpgradeNewerVersion = UpgradeReason(1 + iota) // The replacement has a higher Version.
+	UpgradeUndeprecated                           // The owned file is Deprecated, the replacement isn't.
+	UpgradeBadCRC                                 // The owned file has a BadCRC, the replacement doesn't.
+)
+
+func (r UpgradeReason) String() string {
+	switch r {
+	case UpgradeNewerVersion:
+		return "newer version"
+	case UpgradeUndeprecated:
+		return "not deprecated"
+	case UpgradeBadCRC:
+		return "good CRC"
+	default:
+		return "unknown"
+	}
+}
+
+// A suggested replacement for a file in the mylist.
+type Upgrade struct {
+	Entry *MyListEntry // The mylist entry of the owned file.
+	File  *File        // The owned file.
+
+	Replacement *File
+	Reason      UpgradeReason
+}
+
+// Returns why r would be an upgrade over f, or 0 if it wouldn't be.
+//
+// Only non-deprecated files from the same group, for the same episodes,
+// are considered.
+func upgradeReason(f, r *File) UpgradeReason {
+	switch {
+	case r == nil, r.FID == f.FID, r.GID != f.GID, r.Deprecated, r.BadCRC,
+		r.EpisodeNumber.String() != f.EpisodeNumber.String():
+		return 0
+	case r.Version > f.Version:
+		return UpgradeNewerVersion
+	case f.Deprecated:
+		return UpgradeUndeprecated
+	case f.BadCRC && r.Version == f.Version:
+		return UpgradeBadCRC
+	}
+	return 0
+}
+
+// Returns the best replacement for the given file, if any.
+// Higher versions are preferred.
+func (adb *AniDB) findUpgrade(f *File) (best *File, reason UpgradeReason) {
+	if f.GID < 1 {
+		return
+	}
+	ep := <-adb.EpisodeByID(f.EID)
+	if ep == nil {
+		return
+	}
+
+	for r := range adb.FilesByGID(ep, f.GID) {
+		if why := upgradeReason(f, r); why != 0 {
+			if best == nil || r.Version > best.Version {
+				best, reason = r, why
+			}
+		}
+	}
+	return
+}
+
+// Looks for replacements for every file in the current user's MyListMirror
+// that isn't marked as deleted: newer versions from the same group,
+// non-deprecated files when the owned one is deprecated, and files without
+// a bad CRC when the owned one has one.
+//
+// Returns one Upgrade for every file with a suggested replacement, in no
+// particular order. The list of files comes from the mirror, which should
+// be synced beforehand. Uses the UDP API.
+func (adb *AniDB) FindUpgrades() <-chan *Upgrade {
+	ch := make(chan *Upgrade, 10)
+
+	go func() {
+		defer close(ch)
+
+		user := <-adb.GetCurrentUser()
+		if user == nil || user.UID < 1 {
+			return
+		}
+		m := &MyListMirror{adb: adb, UID: user.UID}
+
+		entries := m.Entries(func(e *MyListEntry) bool {
+			return e.MyListState != MyListStateDeleted && e.FID > 0
+		})
+
+		// bounded, as each file needs several queries
+		sem := make(chan bool, 4)
+		wg := sync.WaitGroup{}
+		for _, e := range entries {
+			wg.Add(1)
+			sem <- true
+			go func(e *MyListEntry) {
+				defer func() { <-sem; wg.Done() }()
+
+				f := <-adb.FileByID(e.FID)
+				if f == nil {
+					return
+				}
+				if r, why := adb.findUpgrade(f); r != nil {
+					ch <- &Upgrade{Entry: e, File: f, Replacement: r, Reason: why}
+				}
+			}(e)
+		}
+		wg.Wait()
+	}()
+	return ch
+}
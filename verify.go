@@ -0,0 +1,203 @@
+package anidb
+
+import (
+	"github.com/Kovensky/go-anidb/hash"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// The outcome of one of Verify's checks.
+type VerifyCheck int
+
+const (
+	VerifySkipped = VerifyCheck(iota) // Nothing to compare against.
+	VerifyPassed
+	VerifyFailed
+)
+
+func (c VerifyCheck) String() string {
+	switch c {
+	case VerifySkipped:
+		return "skipped"
+	case VerifyPassed:
+		return "passed"
+	case VerifyFailed:
+		return "failed"
+	default:
+		return "invalid"
+	}
+}
+
+func verifyCheck(local, remote string) VerifyCheck {
+	switch {
+	case remote == "", local == "":
+		return VerifySkipped
+	case strings.EqualFold(local, remote):
+		return VerifyPassed
+	}
+	return VerifyFailed
+}
+
+// The result of verifying a local file.
+type VerifyResult struct {
+	Path   string
+	Hashes *hash.Hashes // The freshly computed hashes; nil if the file couldn't be read.
+	File   *File        // The File it was compared against; nil if it couldn't be found.
+
+	Size  VerifyCheck
+	Ed2k  VerifyCheck
+	SHA1  VerifyCheck
+	CRC32 VerifyCheck
+
+	// Compares the CRC32 against one embedded in the filename,
+	// as in "[Group] Title - 01 [ABCD1234].mkv".
+	FilenameCRC VerifyCheck
+
+	// Whether AniDB says the file's CRC doesn't match the one in its
+	// release name.
+	BadCRC bool
+}
+
+// Returns true if the file was found in AniDB, no check failed and
+// AniDB doesn't flag the file with a bad CRC.
+func (r *VerifyResult) OK() bool {
+	if r.Hashes == nil || r.File == nil || r.BadCRC {
+		return false
+	}
+	for _, c := range []VerifyCheck{r.Size, r.Ed2k, r.SHA1, r.CRC32, r.FilenameCRC} {
+		if c == VerifyFailed {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns true if the local file doesn't match AniDB's data for it.
+// Unlike OK, doesn't consider the filename CRC or BadCRC, which are
+// usually problems with the release rather than with the local copy.
+func (r *VerifyResult) Corrupted() bool {
+	for _, c := range []VerifyCheck{r.Size, r.Ed2k, r.SHA1, r.CRC32} {
+		if c == VerifyFailed {
+			return true
+		}
+	}
+	return false
+}
+
+var filenameCRC = regexp.MustCompile(`[\[(]([0-9A-Fa-f]{8})[\])]`)
+
+// Returns the last CRC32 embedded in the file name, if any.
+func crcFromFilename(path string) string {
+	m := filenameCRC.FindAllStringSubmatch(filepath.Base(path), -1)
+	if len(m) == 0 {
+		return ""
+	}
+	return m[len(m)-1][1]
+}
+
+// Hashes the local file at the given path, bypassing the hash cache, and
+// compares the result against AniDB's data for the File.
+//
+// The File is found by the hashes, or, if the contents changed but the
+// modification time didn't (as with bitrot), by the hashes cached from
+// when the file was last hashed.
+//
+// The channel returns nil if the file couldn't be read.
+// Uses the UDP API.
+func (adb *AniDB) Verify(path string) <-chan *VerifyResult {
+	ch := make(chan *VerifyResult, 1)
+
+	go func() {
+		cached := (&hash.Hasher{Cache: &Cache}).Cached(path)
+
+		h, err := hash.File(path)
+		if err != nil {
+			ch <- nil
+			close(ch)
+			return
+		}
+
+		f := <-adb.FileByEd2kSize(h.Ed2k, h.Size)
+		if f == nil && cached != nil && *cached != *h {
+			f = <-adb.FileByEd2kSize(cached.Ed2k, cached.Size)
+		}
+
+		ch <- verify(path, h, f)
+		close(ch)
+	}()
+	return ch
+}
+
+// Hashes the local file at the given path, bypassing the hash cache, and
+// compares the result against the given File.
+//
+// The channel returns nil if the file couldn't be read.
+func (adb *AniDB) VerifyFile(path string, f *File) <-chan *VerifyResult {
+	ch := make(chan *VerifyResult, 1)
+
+	go func() {
+		if h, err := hash.File(path); err == nil {
+			ch <- verify(path, h, f)
+		} else {
+			ch <- nil
+		}
+		close(ch)
+	}()
+	return ch
+}
+
+func verify(path string, h *hash.Hashes, f *File) *VerifyResult {
+	r := &VerifyResult{
+		Path:   path,
+		Hashes: h,
+		File:   f,
+
+		FilenameCRC: verifyCheck(h.CRC32, crcFromFilename(path)),
+	}
+	if f == nil {
+		return r
+	}
+
+	r.Size = VerifyFailed
+	if f.Filesize == h.Size {
+		r.Size = VerifyPassed
+	}
+	r.Ed2k = verifyCheck(h.Ed2k, f.Ed2kHash)
+	r.SHA1 = verifyCheck(h.SHA1, f.SHA1Hash)
+	r.CRC32 = verifyCheck(h.CRC32, f.CRC32)
+	r.BadCRC = f.BadCRC
+
+	return r
+}
+
+// Appended by MarkCorrupted to the Other field of mylist entries.
+var CorruptedNote = "[corrupted]"
+
+// Appends CorruptedNote to the Other field of the verified file's mylist
+// entry, if the file is Corrupted. The UDP API can't change an entry's
+// FileState, so this is the only way to record it.
+//
+// Returns whether the entry was edited. Uses the UDP API.
+func (adb *AniDB) MarkCorrupted(r *VerifyResult) <-chan bool {
+	ch := make(chan bool, 1)
+
+	if r == nil || r.File == nil || !r.Corrupted() {
+		ch <- false
+		close(ch)
+		return ch
+	}
+
+	go func() {
+		e := <-adb.MyListByFile(r.File)
+		switch {
+		case e == nil, strings.Contains(e.Other, CorruptedNote):
+			ch <- false
+		default:
+			other := strings.TrimSpace(e.Other + " " + CorruptedNote)
+			ch <- <-adb.MyListEdit(r.File, &MyListSet{Other: &other})
+		}
+		close(ch)
+	}()
+	return ch
+}
@@ -0,0 +1,32 @@
+package anidb
+
+import (
+	"github.com/Kovensky/go-anidb/hash"
+	"testing"
+)
+
+func TestVerify(T *testing.T) {
+	T.Parallel()
+
+	h := &hash.Hashes{Size: 1024, Ed2k: "0123456789abcdef0123456789abcdef", CRC32: "abcd1234"}
+	path := "/anime/[Group] Title - 01 [ABCD1234].mkv"
+
+	r := verify(path, h, &File{Filesize: 1024, Ed2kHash: h.Ed2k, CRC32: h.CRC32})
+	if !r.OK() || r.Corrupted() {
+		T.Errorf("Expected a matching file to be OK: %#v", r)
+	}
+
+	r = verify(path, h, &File{Filesize: 1024, Ed2kHash: h.Ed2k, CRC32: "deadbeef"})
+	if r.OK() || !r.Corrupted() {
+		T.Errorf("Expected a mismatching file to be corrupted: %#v", r)
+	}
+
+	// passes the filename check, but there's nothing else to compare against
+	r = verify(path, h, nil)
+	if r.FilenameCRC != VerifyPassed {
+		T.Error("Wrong FilenameCRC:", r.FilenameCRC)
+	}
+	if r.OK() || r.Corrupted() {
+		T.Errorf("Expected an unknown file to be neither OK nor corrupted: %#v", r)
+	}
+}
@@ -0,0 +1,71 @@
+package anidb
+
+import (
+	"github.com/Kovensky/go-anidb/misc"
+	"time"
+)
+
+// See the constants list for valid values.
+type VoteType int
+
+const (
+	VoteTypeAnime          = VoteType(1 + iota) // Permanent anime vote; for people who finished watching it
+	VoteTypeAnimeTemporary                      // Temporary anime vote; for people still watching it
+	VoteTypeGroup                               // Group vote
+
+	// Not an API vote type: episode votes are sent as anime votes with an episode number.
+	VoteTypeEpisode = VoteType(100)
+)
+
+func (vt VoteType) String() string {
+	switch vt {
+	case VoteTypeAnime:
+		return "Anime"
+	case VoteTypeAnimeTemporary:
+		return "Anime (Temporary)"
+	case VoteTypeGroup:
+		return "Group"
+	case VoteTypeEpisode:
+		return "Episode"
+	default:
+		return "Unknown"
+	}
+}
+
+// The thing being voted on. Use the AnimeVote, TemporaryAnimeVote,
+// EpisodeVote and GroupVote functions to create one.
+type VoteTarget struct {
+	Type VoteType
+	ID   int // The AID for anime and episodes, the GID for groups
+
+	Episode *misc.Episode // Only used by episode votes
+}
+
+func AnimeVote(aid AID) VoteTarget {
+	return VoteTarget{Type: VoteTypeAnime, ID: int(aid)}
+}
+
+func TemporaryAnimeVote(aid AID) VoteTarget {
+	return VoteTarget{Type: VoteTypeAnimeTemporary, ID: int(aid)}
+}
+
+func EpisodeVote(ep *Episode) VoteTarget {
+	if ep == nil {
+		return VoteTarget{Type: VoteTypeEpisode}
+	}
+	return VoteTarget{Type: VoteTypeEpisode, ID: int(ep.AID), Episode: &ep.Episode}
+}
+
+func GroupVote(gid GID) VoteTarget {
+	return VoteTarget{Type: VoteTypeGroup, ID: int(gid)}
+}
+
+// A vote by the current user.
+type Vote struct {
+	Target VoteTarget
+
+	Name  string  // Name of the voted entity, as given by the server
+	Value float32 // Range 1.00-10.00; 0 if there's no vote
+
+	Cached time.Time
+}
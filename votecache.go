@@ -0,0 +1,251 @@
+package anidb
+
+import (
+	"github.com/Kovensky/go-anidb/misc"
+	"github.com/Kovensky/go-anidb/udp"
+	"github.com/Kovensky/go-fscache"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var _ cacheable = &Vote{}
+
+func (v *Vote) setCachedTS(ts time.Time) {
+	v.Cached = ts
+}
+
+func (v *Vote) IsStale() bool {
+	if v == nil {
+		return true
+	}
+	return time.Now().Sub(v.Cached) > VoteCacheDuration
+}
+
+func (t VoteTarget) valid() bool {
+	switch t.Type {
+	case VoteTypeAnime, VoteTypeAnimeTemporary, VoteTypeGroup:
+		return t.ID > 0
+	case VoteTypeEpisode:
+		return t.ID > 0 && t.Episode != nil
+	}
+	return false
+}
+
+func (t VoteTarget) cacheKey(uid UID) []fscache.CacheKey {
+	key := []fscache.CacheKey{"vote", uid, int(t.Type), t.ID}
+	if t.Type == VoteTypeEpisode {
+		key = append(key, t.Episode.String())
+	}
+	return key
+}
+
+// Converts the episode into the integer form used by the VOTE command,
+// where non-regular episodes are numbered after an offset for their type
+// (S1 is 1001, C1 is 2001, etc).
+func voteEpisodeNumber(ep *misc.Episode) int {
+	switch ep.Type {
+	case misc.EpisodeTypeSpecial:
+		return 1000 + ep.Number
+	case misc.EpisodeTypeCredits:
+		return 2000 + ep.Number
+	case misc.EpisodeTypeTrailer:
+		return 3000 + ep.Number
+	case misc.EpisodeTypeParody:
+		return 4000 + ep.Number
+	case misc.EpisodeTypeOther:
+		return 5000 + ep.Number
+	}
+	return ep.Number
+}
+
+func (t VoteTarget) toParamMap() paramMap {
+	if t.Type == VoteTypeEpisode {
+		return paramMap{
+			"type": int(VoteTypeAnime),
+			"id":   t.ID,
+			"epno": voteEpisodeNumber(t.Episode),
+		}
+	}
+	return paramMap{
+		"type": int(t.Type),
+		"id":   t.ID,
+	}
+}
+
+// Retrieves the current user's cached vote for the given target.
+func (u *User) Vote(target VoteTarget) *Vote {
+	if u == nil || !target.valid() {
+		return nil
+	}
+	var v Vote
+	if CacheGet(&v, target.cacheKey(u.UID)...) == nil {
+		return &v
+	}
+	return nil
+}
+
+// Retrieves the current user's vote for the given target.
+// If the user hasn't voted, the returned Vote's Value is 0.
+// Uses the UDP API.
+func (adb *AniDB) MyVote(target VoteTarget) <-chan *Vote {
+	ch := make(chan *Vote, 1)
+
+	if !target.valid() {
+		ch <- nil
+		close(ch)
+		return ch
+	}
+
+	go func() {
+		user := <-adb.GetCurrentUser()
+		if user == nil || user.UID < 1 {
+			ch <- nil
+			close(ch)
+			return
+		}
+
+		key := target.cacheKey(user.UID)
+
+		ic := make(chan notification, 1)
+		go func() { ch <- (<-ic).(*Vote); close(ch) }()
+		if intentMap.Intent(ic, key...) {
+			return
+		}
+
+		v := user.Vote(target)
+		if !v.IsStale() {
+			intentMap.NotifyClose(v, key...)
+			return
+		}
+
+		pm := target.toParamMap()
+		pm["value"] = 0
+
+		reply := <-adb.udp.SendRecv("VOTE", pm)
+
+		switch reply.Code() {
+		case 261: // vote found
+			v = parseVoteReply(target, reply)
+			CacheSet(v, key...)
+		case 360: // no such vote
+			v = &Vote{Target: target}
+			CacheSet(v, key...)
+		}
+
+		intentMap.NotifyClose(v, key...)
+	}()
+	return ch
+}
+
+// Votes on the given target. The value must be in the 1.00-10.00 range.
+//
+// Returns the new Vote, or nil if the vote failed. Uses the UDP API.
+func (adb *AniDB) Vote(target VoteTarget, value float32) <-chan *Vote {
+	if value < 1 || value > 10 {
+		ch := make(chan *Vote, 1)
+		ch <- nil
+		close(ch)
+		return ch
+	}
+	return adb.vote(target, int(value*100+0.5))
+}
+
+// Revokes the current user's vote on the given target.
+//
+// Returns whether the vote was revoked. Uses the UDP API.
+func (adb *AniDB) RevokeVote(target VoteTarget) <-chan bool {
+	ch := make(chan bool, 1)
+
+	go func() {
+		v := <-adb.vote(target, -1)
+		ch <- v != nil && v.Value == 0
+		close(ch)
+	}()
+	return ch
+}
+
+func (adb *AniDB) vote(target VoteTarget, value int) <-chan *Vote {
+	ch := make(chan *Vote, 1)
+
+	if !target.valid() {
+		ch <- nil
+		close(ch)
+		return ch
+	}
+
+	go func() {
+		user := <-adb.GetCurrentUser()
+		if user == nil || user.UID < 1 {
+			ch <- nil
+			close(ch)
+			return
+		}
+
+		// for the intent map; doesn't get cached
+		key := append([]fscache.CacheKey{"vote-set"}, target.cacheKey(user.UID)[1:]...)
+
+		ic := make(chan notification, 1)
+		go func() { ch <- (<-ic).(*Vote); close(ch) }()
+		if intentMap.Intent(ic, key...) {
+			return
+		}
+
+		pm := target.toParamMap()
+		pm["value"] = value
+
+		reply := <-adb.udp.SendRecv("VOTE", pm)
+
+		var v *Vote
+		switch reply.Code() {
+		case 260: // voted
+			v = parseVoteReply(target, reply)
+			updateVoteCount(user.UID, 1)
+		case 262: // vote updated
+			v = parseVoteReply(target, reply)
+		case 263: // vote revoked
+			v = &Vote{Target: target}
+			updateVoteCount(user.UID, -1)
+		}
+
+		if v != nil {
+			CacheSet(v, target.cacheKey(user.UID)...)
+		}
+
+		intentMap.NotifyClose(v, key...)
+	}()
+	return ch
+}
+
+func parseVoteReply(target VoteTarget, reply udpapi.APIReply) *Vote {
+	parts := strings.Split(reply.Lines()[1], "|")
+	if len(parts) < 2 {
+		return &Vote{Target: target}
+	}
+
+	value, _ := strconv.ParseInt(parts[1], 10, 32)
+
+	return &Vote{
+		Target: target,
+
+		Name:  parts[0],
+		Value: float32(value) / 100,
+	}
+}
+
+// Reflects a vote or revocation into the cached MyListStats,
+// without changing its cache timestamp.
+func updateVoteCount(uid UID, delta int) {
+	var s MyListStats
+	if CacheGet(&s, "mylist-stats", uid) != nil {
+		return
+	}
+
+	s.Votes += delta
+	if s.Votes < 0 {
+		s.Votes = 0
+	}
+
+	Cache.Set(&s, "mylist-stats", uid)
+	Cache.Chtime(s.Cached, "mylist-stats", uid)
+}
@@ -0,0 +1,30 @@
+package anidb
+
+import (
+	"github.com/Kovensky/go-anidb/misc"
+	"testing"
+)
+
+func TestEpisodeVoteParams(T *testing.T) {
+	T.Parallel()
+
+	for _, test := range []struct {
+		epno string
+		exp  int
+	}{
+		{"5", 5},
+		{"S1", 1001},
+		{"S12", 1012},
+		{"C2", 2002},
+	} {
+		ep := &Episode{AID: 1, Episode: *misc.ParseEpisode(test.epno)}
+		pm := EpisodeVote(ep).toParamMap()
+
+		if pm["type"] != int(VoteTypeAnime) || pm["id"] != 1 {
+			T.Errorf("%s: wrong target %v", test.epno, pm)
+		}
+		if pm["epno"] != test.exp {
+			T.Errorf("%s: expected epno %d, got %v", test.epno, test.exp, pm["epno"])
+		}
+	}
+}
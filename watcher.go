@@ -0,0 +1,337 @@
+package anidb
+
+import (
+	"github.com/Kovensky/go-fscache"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+type WatchEventType int
+
+const (
+	WatchAdded   = WatchEventType(1 + iota) // A new file was identified and added to the mylist.
+	WatchMoved                              // A known file was moved or renamed.
+	WatchRemoved                            // A file was removed, and marked as deleted in the mylist.
+	WatchUnknown                            // A new file isn't known to AniDB.
+	WatchFailed                             // A file couldn't be hashed, or the API failed.
+)
+
+func (t WatchEventType) String() string {
+	switch t {
+	case WatchAdded:
+		return "added"
+	case WatchMoved:
+		return "moved"
+	case WatchRemoved:
+		return "removed"
+	case WatchUnknown:
+		return "unknown"
+	case WatchFailed:
+		return "failed"
+	default:
+		return "invalid"
+	}
+}
+
+type WatchEvent struct {
+	Type    WatchEventType
+	Path    string
+	OldPath string // set on WatchMoved
+	File    *File  // nil on WatchUnknown, and on WatchFailed for unidentified files
+	Err     error
+}
+
+// What the watcher knows about a file on disk.
+type watchedFile struct {
+	Size    int64
+	ModTime time.Time
+	FID     FID // 0 if unknown to AniDB
+}
+
+// Watches directories for video files, and keeps the mylist in sync with
+// them: new files are identified and added to the mylist (or edited to
+// match Set, if already there), and files removed from disk are marked as
+// deleted.
+//
+// On Linux, inotify is used to notice changes as they happen; elsewhere,
+// or if inotify is unavailable, the directories are polled. What was seen on
+// disk is stored in the cache, so files removed while the watcher wasn't
+// running are noticed too.
+type Watcher struct {
+	adb *AniDB
+
+	Dirs []string
+
+	// Only files with these extensions are watched; matched
+	// case-insensitively. Defaults to VideoExtensions.
+	Extensions []string
+
+	// How often the directories are rescanned (default: 5 minutes).
+	// A rescan is still done every Interval when using inotify.
+	Interval time.Duration
+
+	// How long a file must go unmodified before it's identified, so files
+	// that are still being written aren't hashed (default: 10 seconds).
+	Settle time.Duration
+
+	// Applied to new files; defaults to the HDD state.
+	Set *MyListSet
+
+	// If not nil, receives an event for every change handled.
+	Events chan<- *WatchEvent
+
+	files map[string]*watchedFile
+}
+
+// Returns a Watcher for the given directories. Call Run to start it.
+func (adb *AniDB) NewWatcher(dirs ...string) *Watcher {
+	state := MyListStateHDD
+	return &Watcher{
+		adb:        adb,
+		Dirs:       dirs,
+		Extensions: VideoExtensions,
+		Interval:   5 * time.Minute,
+		Settle:     10 * time.Second,
+		Set:        &MyListSet{State: &state},
+	}
+}
+
+func (w *Watcher) cacheKey() []fscache.CacheKey {
+	dirs := make([]string, len(w.Dirs))
+	for i, d := range w.Dirs {
+		if abs, err := filepath.Abs(d); err == nil {
+			d = abs
+		}
+		dirs[i] = d
+	}
+	sort.Strings(dirs)
+	return []fscache.CacheKey{"watcher", strings.Join(dirs, "|")}
+}
+
+func (w *Watcher) event(ev *WatchEvent) {
+	if w.Events != nil {
+		w.Events <- ev
+	}
+}
+
+// Watches the directories until the stop channel is closed.
+func (w *Watcher) Run(stop <-chan bool) {
+	w.files = map[string]*watchedFile{}
+	Cache.Get(&w.files, w.cacheKey()...)
+
+	wake := make(chan bool, 1)
+	n, err := newNotifier(wake)
+	if err != nil {
+		w.adb.Logger.Println("watcher: falling back to polling:", err)
+	} else {
+		defer n.Close()
+	}
+
+	interval := w.Interval
+	if interval <= 0 {
+		interval = 5 * time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var retry <-chan time.Time
+	for {
+		dirs, pending := w.scan()
+		if n != nil {
+			for _, d := range dirs {
+				n.Add(d)
+			}
+		}
+		if pending {
+			retry = time.After(w.settle())
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		case <-retry:
+		case <-wake:
+			// let bursts of events settle down
+			time.Sleep(time.Second)
+			select {
+			case <-wake:
+			default:
+			}
+		}
+	}
+}
+
+func (w *Watcher) settle() time.Duration {
+	if w.Settle <= 0 {
+		return 10 * time.Second
+	}
+	return w.Settle
+}
+
+// Scans the directories once, and handles the differences from the
+// previous scan. Returns the directories found, and whether there were
+// files that were too recently modified to be handled.
+func (w *Watcher) scan() (dirs []string, pending bool) {
+	exts := w.Extensions
+	if exts == nil {
+		exts = VideoExtensions
+	}
+
+	seen := map[string]os.FileInfo{}
+	for _, root := range w.Dirs {
+		filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			switch {
+			case err != nil:
+				return nil
+			case info.IsDir():
+				dirs = append(dirs, path)
+			case info.Mode().IsRegular() && hasExtension(path, exts):
+				seen[path] = info
+			}
+			return nil
+		})
+	}
+
+	removed := map[string]*watchedFile{}
+	for path, wf := range w.files {
+		if info, ok := seen[path]; !ok {
+			removed[path] = wf
+		} else if info.Size() != wf.Size || !info.ModTime().Equal(wf.ModTime) {
+			// changed; handle as a new file
+			delete(w.files, path)
+		}
+	}
+	for path := range removed {
+		delete(w.files, path)
+	}
+
+	paths := make([]string, 0, len(seen))
+	for path := range seen {
+		if w.files[path] == nil {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		info := seen[path]
+		if time.Now().Sub(info.ModTime()) < w.settle() {
+			pending = true
+			continue
+		}
+
+		if old, wf := w.findMoved(removed, info); wf != nil {
+			delete(removed, old)
+			w.files[path] = wf
+			w.event(&WatchEvent{Type: WatchMoved, Path: path, OldPath: old, File: wf.FID.File()})
+			continue
+		}
+
+		wf := &watchedFile{Size: info.Size(), ModTime: info.ModTime()}
+		if w.add(path, wf) {
+			w.files[path] = wf
+		}
+	}
+
+	for path, wf := range removed {
+		if !w.remove(path, wf) {
+			// try again next time
+			w.files[path] = wf
+		}
+	}
+
+	Cache.Set(&w.files, w.cacheKey()...)
+	return
+}
+
+// Looks for a removed file that looks like the given one.
+// Renames keep the size and modification time.
+func (w *Watcher) findMoved(removed map[string]*watchedFile, info os.FileInfo) (string, *watchedFile) {
+	for path, wf := range removed {
+		if wf.Size == info.Size() && wf.ModTime.Equal(info.ModTime()) {
+			return path, wf
+		}
+	}
+	return "", nil
+}
+
+// Identifies a new file and adds it to the mylist.
+// Returns false if it should be tried again on the next scan.
+func (w *Watcher) add(path string, wf *watchedFile) bool {
+	h, err := HashFile(path)
+	if err != nil {
+		w.event(&WatchEvent{Type: WatchFailed, Path: path, Err: err})
+		return false
+	}
+
+	r := &IdentifyResult{Path: path, Hashes: h}
+	w.adb.identify(r, PreferNonDeprecated)
+	switch r.Status {
+	case IdentifyUnknown:
+		w.event(&WatchEvent{Type: WatchUnknown, Path: path})
+		return true
+	case IdentifyError:
+		w.event(&WatchEvent{Type: WatchFailed, Path: path})
+		return false
+	}
+	f := r.File
+	wf.FID = f.FID
+
+	user := <-w.adb.GetCurrentUser()
+	if user == nil || user.UID < 1 {
+		w.event(&WatchEvent{Type: WatchFailed, Path: path, File: f})
+		return false
+	}
+
+	e := f.UserMyList(user)
+	if e == nil {
+		lid := <-w.adb.MyListAdd(f, w.Set)
+		if lid < 1 {
+			w.event(&WatchEvent{Type: WatchFailed, Path: path, File: f})
+			return false
+		}
+		// already in the mylist if the add didn't apply Set
+		e = lid.MyListEntry()
+	}
+	if w.Set.changes(e) && !<-w.adb.MyListEdit(f, w.Set) {
+		w.event(&WatchEvent{Type: WatchFailed, Path: path, File: f})
+		return false
+	}
+
+	w.event(&WatchEvent{Type: WatchAdded, Path: path, File: f})
+	return true
+}
+
+// Marks a removed file as deleted in the mylist, unless there's
+// still another copy of it. Returns false if it should be tried again
+// on the next scan.
+func (w *Watcher) remove(path string, wf *watchedFile) bool {
+	if wf.FID < 1 {
+		return true
+	}
+	for _, other := range w.files {
+		if other.FID == wf.FID {
+			return true
+		}
+	}
+
+	f := <-w.adb.FileByID(wf.FID)
+	state := MyListStateDeleted
+	if f == nil || !<-w.adb.MyListEdit(f, &MyListSet{State: &state}) {
+		w.event(&WatchEvent{Type: WatchFailed, Path: path, File: f})
+		return false
+	}
+
+	w.event(&WatchEvent{Type: WatchRemoved, Path: path, File: f})
+	return true
+}
+
+// Wakes the Watcher when the watched directories change.
+type notifier interface {
+	Add(dir string) error
+	Close() error
+}
@@ -0,0 +1,47 @@
+package anidb
+
+import (
+	"os"
+	"syscall"
+)
+
+const inotifyMask = syscall.IN_CLOSE_WRITE | syscall.IN_CREATE | syscall.IN_DELETE |
+	syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO
+
+type inotifyNotifier struct {
+	fd   int
+	file *os.File
+}
+
+// Wakes the Watcher when inotify reports changes in a watched directory.
+func newNotifier(wake chan<- bool) (notifier, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_NONBLOCK | syscall.IN_CLOEXEC)
+	if err != nil {
+		return nil, err
+	}
+	n := &inotifyNotifier{fd: fd, file: os.NewFile(uintptr(fd), "inotify")}
+
+	go func() {
+		buf := make([]byte, 64*1024)
+		for {
+			if _, err := n.file.Read(buf); err != nil {
+				return
+			}
+			// the scan finds out what changed
+			select {
+			case wake <- true:
+			default:
+			}
+		}
+	}()
+	return n, nil
+}
+
+func (n *inotifyNotifier) Add(dir string) error {
+	_, err := syscall.InotifyAddWatch(n.fd, dir, inotifyMask)
+	return err
+}
+
+func (n *inotifyNotifier) Close() error {
+	return n.file.Close()
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+// +build !linux
+
+package anidb
+
+import (
+	"errors"
+)
+
+// Only polling is supported.
+func newNotifier(wake chan<- bool) (notifier, error) {
+	return nil, errors.New("no change notification support")
+}
@@ -0,0 +1,167 @@
+package anidb
+
+import (
+	"github.com/Kovensky/go-anidb/misc"
+	"sort"
+	"time"
+)
+
+// How far the current user is in watching an anime.
+//
+// Only regular episodes are taken into account.
+type WatchProgress struct {
+	AID AID
+
+	// The first regular episode that wasn't watched yet, or nil if
+	// every known regular episode was watched.
+	NextEpisode *Episode
+
+	// Whether the next episode is in the mylist with the HDD state.
+	Available bool
+	// The groups that have files for the next episode in the mylist.
+	Groups []GID
+
+	Watched int // Number of watched regular episodes.
+	Total   int // Number of regular episodes; TotalEpisodes if known.
+
+	Remaining time.Duration // Sum of the lengths of the unwatched regular episodes.
+	Percent   float64       // Completion percentage, from 0 to 100.
+
+	LastWatched time.Time // Most recent DateWatched in the mirror, if any.
+}
+
+func (p *WatchProgress) Anime() *Anime {
+	return p.AID.Anime()
+}
+
+// Returns true if all regular episodes were watched.
+func (p *WatchProgress) Complete() bool {
+	return p.NextEpisode == nil && p.Watched >= p.Total
+}
+
+type episodesByNumber []*Episode
+
+func (l episodesByNumber) Len() int           { return len(l) }
+func (l episodesByNumber) Less(i, j int) bool { return l[i].Number < l[j].Number }
+func (l episodesByNumber) Swap(i, j int)      { l[i], l[j] = l[j], l[i] }
+
+func newWatchProgress(a *Anime, mla *MyListAnime) *WatchProgress {
+	p := &WatchProgress{AID: a.AID}
+
+	regular := episodesByNumber{}
+	for _, ep := range a.Episodes {
+		if ep.Type == misc.EpisodeTypeRegular {
+			regular = append(regular, ep)
+		}
+	}
+	sort.Sort(regular)
+
+	for _, ep := range regular {
+		if mla.WatchedEpisodes.ContainsEpisodes(&ep.Episode) {
+			p.Watched++
+			continue
+		}
+		if p.NextEpisode == nil {
+			p.NextEpisode = ep
+		}
+		p.Remaining += ep.Length
+	}
+
+	p.Total = a.TotalEpisodes
+	if p.Total < len(regular) {
+		p.Total = len(regular)
+	}
+	if p.Total > 0 {
+		p.Percent = 100 * float64(p.Watched) / float64(p.Total)
+	}
+
+	if next := p.NextEpisode; next != nil {
+		p.Available = mla.EpisodesWithState[MyListStateHDD].ContainsEpisodes(&next.Episode)
+
+		gids := []int{}
+		for gid, el := range mla.EpisodesPerGroup {
+			if el.ContainsEpisodes(&next.Episode) {
+				gids = append(gids, int(gid))
+			}
+		}
+		sort.Ints(gids)
+		for _, gid := range gids {
+			p.Groups = append(p.Groups, GID(gid))
+		}
+	}
+
+	return p
+}
+
+// Returns the current user's watch progress for the given anime.
+//
+// Returns nil if the anime isn't in the mylist, or on API errors.
+func (adb *AniDB) WatchProgress(aid AID) <-chan *WatchProgress {
+	ch := make(chan *WatchProgress, 1)
+
+	go func() {
+		ach := adb.AnimeByID(aid)
+		mla := <-adb.MyListAnime(aid)
+		a := <-ach
+
+		if a == nil || mla == nil {
+			ch <- nil
+			close(ch)
+			return
+		}
+
+		p := newWatchProgress(a, mla)
+		if m := adb.MyListMirror(); m != nil {
+			for _, e := range m.EntriesForAnime(aid) {
+				if e.DateWatched.After(p.LastWatched) {
+					p.LastWatched = e.DateWatched
+				}
+			}
+		}
+
+		ch <- p
+		close(ch)
+	}()
+	return ch
+}
+
+type watchQueue []*WatchProgress
+
+func (q watchQueue) Len() int           { return len(q) }
+func (q watchQueue) Less(i, j int) bool { return q[i].LastWatched.After(q[j].LastWatched) }
+func (q watchQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+
+// Returns the watch progress of every anime in the current user's
+// MyListMirror that was started but not finished, most recently
+// watched first.
+//
+// The list of anime comes from the mirror, which should be synced
+// beforehand.
+func (adb *AniDB) WatchQueue() <-chan *WatchProgress {
+	ch := make(chan *WatchProgress, 10)
+
+	go func() {
+		defer close(ch)
+
+		user := <-adb.GetCurrentUser()
+		if user == nil || user.UID < 1 {
+			return
+		}
+		m := &MyListMirror{adb: adb, UID: user.UID}
+
+		queue := watchQueue{}
+		for _, aid := range m.AIDs() {
+			p := <-adb.WatchProgress(aid)
+			if p == nil || p.Watched == 0 || p.NextEpisode == nil {
+				continue
+			}
+			queue = append(queue, p)
+		}
+		sort.Stable(queue)
+
+		for _, p := range queue {
+			ch <- p
+		}
+	}()
+	return ch
+}